@@ -0,0 +1,198 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+)
+
+// Balances maps runes to amounts.
+type Balances map[RuneId]uint128.Uint128
+
+// Add adds amount of id to b.
+func (b Balances) Add(id RuneId, amount uint128.Uint128) {
+	b[id] = b[id].Add(amount)
+}
+
+// Allocation is where the runes held by the inputs of a transaction end up.
+type Allocation struct {
+	// Outputs has the balances of every output of the transaction. It is
+	// empty for OP_RETURN outputs, whose runes are burned.
+	Outputs []Balances
+	Burned  Balances
+}
+
+// Allocate moves the runes held by the inputs of tx to its outputs, the way
+// ord's rune updater does: edicts are applied in order, what is left goes to
+// the pointer or else the first non-OP_RETURN output, and runes sent to
+// OP_RETURN outputs, left without a destination or spent by a cenotaph are
+// burned.
+//
+// artifact is the result of deciphering tx, or nil if it has none. inputs are
+// the balances of the outputs spent by tx, in input order. minted is the amount
+// received by the mint of artifact, which is zero if the mint fails, and etched
+// is the id of the rune etched by tx if its etching is valid. Both depend on
+// chain state, see RuneEntry.Mintable and ValidateCommitment.
+func Allocate(tx *wire.MsgTx, artifact *Artifact, inputs []Balances, minted uint128.Uint128, etched *RuneId) *Allocation {
+	unallocated := make(Balances)
+	for _, balances := range inputs {
+		for id, amount := range balances {
+			unallocated.Add(id, amount)
+		}
+	}
+	allocation := &Allocation{
+		Outputs: make([]Balances, len(tx.TxOut)),
+		Burned:  make(Balances),
+	}
+	for i := range allocation.Outputs {
+		allocation.Outputs[i] = make(Balances)
+	}
+
+	if artifact != nil {
+		if id := artifact.Mint(); id != nil && !minted.IsZero() {
+			unallocated.Add(*id, minted)
+		}
+	}
+
+	if artifact != nil && artifact.Runestone != nil {
+		runestone := artifact.Runestone
+		if etched != nil && runestone.Etching != nil && runestone.Etching.Premine != nil {
+			unallocated.Add(*etched, *runestone.Etching.Premine)
+		}
+		for _, edict := range runestone.Edicts {
+			allocateEdict(tx, edict, etched, unallocated, allocation.Outputs)
+		}
+	}
+
+	if artifact != nil && artifact.Cenotaph != nil {
+		for id, balance := range unallocated {
+			allocation.Burned.Add(id, balance)
+		}
+	} else {
+		vout := -1
+		if artifact != nil && artifact.Runestone.Pointer != nil {
+			vout = int(*artifact.Runestone.Pointer)
+		} else {
+			vout = firstNonOpReturn(tx)
+		}
+		for id, balance := range unallocated {
+			if balance.IsZero() {
+				continue
+			}
+			if vout >= 0 {
+				allocation.Outputs[vout].Add(id, balance)
+			} else {
+				allocation.Burned.Add(id, balance)
+			}
+		}
+	}
+
+	for vout, balances := range allocation.Outputs {
+		if !isOpReturn(tx.TxOut[vout].PkScript) {
+			continue
+		}
+		for id, balance := range balances {
+			allocation.Burned.Add(id, balance)
+		}
+		allocation.Outputs[vout] = make(Balances)
+	}
+	for id, balance := range allocation.Burned {
+		if balance.IsZero() {
+			delete(allocation.Burned, id)
+		}
+	}
+	return allocation
+}
+
+// allocateEdict moves the amount of edict out of unallocated. An edict whose
+// output is len(tx.TxOut) goes to every non-OP_RETURN output: its amount to
+// each of them, or with a zero amount the whole balance split evenly, the
+// remainder going to the first outputs.
+func allocateEdict(tx *wire.MsgTx, edict Edict, etched *RuneId, unallocated Balances, outputs []Balances) {
+	id := edict.ID
+	if id == (RuneId{}) {
+		if etched == nil {
+			return
+		}
+		id = *etched
+	}
+	balance, ok := unallocated[id]
+	if !ok {
+		return
+	}
+
+	allocate := func(amount uint128.Uint128, output int) {
+		if amount.IsZero() {
+			return
+		}
+		balance = balance.Sub(amount)
+		outputs[output].Add(id, amount)
+	}
+
+	if int(edict.Output) == len(tx.TxOut) {
+		var destinations []int
+		for i, out := range tx.TxOut {
+			if !isOpReturn(out.PkScript) {
+				destinations = append(destinations, i)
+			}
+		}
+		if len(destinations) > 0 {
+			if edict.Amount.IsZero() {
+				amount := balance.Div64(uint64(len(destinations)))
+				remainder := int(balance.Mod64(uint64(len(destinations))))
+				for i, output := range destinations {
+					if i < remainder {
+						allocate(amount.Add64(1), output)
+					} else {
+						allocate(amount, output)
+					}
+				}
+			} else {
+				for _, output := range destinations {
+					allocate(minUint128(edict.Amount, balance), output)
+				}
+			}
+		}
+	} else {
+		amount := balance
+		if !edict.Amount.IsZero() {
+			amount = minUint128(edict.Amount, balance)
+		}
+		allocate(amount, int(edict.Output))
+	}
+	unallocated[id] = balance
+}
+
+func firstNonOpReturn(tx *wire.MsgTx) int {
+	for i, out := range tx.TxOut {
+		if !isOpReturn(out.PkScript) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isOpReturn(pkScript []byte) bool {
+	return len(pkScript) > 0 && pkScript[0] == txscript.OP_RETURN
+}
+
+func minUint128(a, b uint128.Uint128) uint128.Uint128 {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
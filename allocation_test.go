@@ -0,0 +1,106 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"lukechampine.com/uint128"
+)
+
+var allocationPkScript = append([]byte{txscript.OP_0, txscript.OP_DATA_20}, make([]byte, 20)...)
+
+// allocationTx returns a transaction with an OP_RETURN output carrying
+// runestone followed by the given number of other outputs.
+func allocationTx(t *testing.T, runestone *Runestone, outputs int) (*wire.MsgTx, *Artifact) {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	script, err := runestone.Encipher()
+	assert.NoError(t, err)
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	for i := 0; i < outputs; i++ {
+		tx.AddTxOut(wire.NewTxOut(1000, allocationPkScript))
+	}
+	artifact, err := (&Runestone{}).Decipher(tx)
+	assert.NoError(t, err)
+	return tx, artifact
+}
+
+func TestAllocate(t *testing.T) {
+	a := RuneId{Block: 1, Tx: 1}
+	b := RuneId{Block: 2, Tx: 3}
+	inputs := []Balances{{a: uint128.From64(100)}, {a: uint128.From64(1), b: uint128.From64(7)}}
+	caseFunc := func(runestone *Runestone, outputs int, expected []Balances, burned Balances) {
+		tx, artifact := allocationTx(t, runestone, outputs)
+		allocation := Allocate(tx, artifact, inputs, uint128.Zero, nil)
+		assert.Equal(t, expected, allocation.Outputs)
+		assert.Equal(t, burned, allocation.Burned)
+	}
+
+	// everything goes to the first non-OP_RETURN output by default
+	caseFunc(&Runestone{}, 2, []Balances{{}, {a: uint128.From64(101), b: uint128.From64(7)}, {}}, Balances{})
+	caseFunc(&Runestone{Pointer: Uint32P(2)}, 2, []Balances{{}, {}, {a: uint128.From64(101), b: uint128.From64(7)}}, Balances{})
+	// or is burned without one
+	caseFunc(&Runestone{}, 0, []Balances{{}}, Balances{a: uint128.From64(101), b: uint128.From64(7)})
+	caseFunc(&Runestone{Pointer: Uint32P(0)}, 1, []Balances{{}, {}}, Balances{a: uint128.From64(101), b: uint128.From64(7)})
+
+	// edicts are applied in order, capped by what is left
+	caseFunc(&Runestone{Edicts: []Edict{
+		{ID: a, Amount: uint128.From64(60), Output: 2},
+		{ID: a, Amount: uint128.From64(60), Output: 1},
+		{ID: b, Amount: uint128.Zero, Output: 2},
+	}}, 2, []Balances{{}, {a: uint128.From64(41)}, {a: uint128.From64(60), b: uint128.From64(7)}}, Balances{})
+	caseFunc(&Runestone{Edicts: []Edict{{ID: a, Amount: uint128.From64(10), Output: 0}}}, 1,
+		[]Balances{{}, {a: uint128.From64(91), b: uint128.From64(7)}}, Balances{a: uint128.From64(10)})
+	// edicts of runes the inputs do not hold do nothing
+	caseFunc(&Runestone{Edicts: []Edict{{ID: RuneId{Block: 9, Tx: 9}, Amount: uint128.From64(10), Output: 2}}}, 2,
+		[]Balances{{}, {a: uint128.From64(101), b: uint128.From64(7)}, {}}, Balances{})
+
+	// output len(tx.TxOut) splits evenly over the non-OP_RETURN outputs
+	caseFunc(&Runestone{Edicts: []Edict{{ID: a, Amount: uint128.Zero, Output: 4}}}, 3,
+		[]Balances{{}, {a: uint128.From64(34), b: uint128.From64(7)}, {a: uint128.From64(34)}, {a: uint128.From64(33)}}, Balances{})
+	caseFunc(&Runestone{Edicts: []Edict{{ID: a, Amount: uint128.From64(40), Output: 4}}}, 3,
+		[]Balances{{}, {a: uint128.From64(40), b: uint128.From64(7)}, {a: uint128.From64(40)}, {a: uint128.From64(21)}}, Balances{})
+
+	// cenotaphs burn everything
+	caseFunc(&Runestone{Edicts: []Edict{{ID: a, Amount: uint128.From64(10), Output: 9}}}, 2,
+		[]Balances{{}, {}, {}}, Balances{a: uint128.From64(101), b: uint128.From64(7)})
+}
+
+func TestAllocateMintAndEtching(t *testing.T) {
+	minted := RuneId{Block: 5, Tx: 1}
+	etched := RuneId{Block: 10, Tx: 2}
+	tx, artifact := allocationTx(t, &Runestone{
+		Etching: &Etching{Premine: uint128From(50)},
+		Mint:    &minted,
+		Edicts:  []Edict{{ID: RuneId{}, Amount: uint128.From64(20), Output: 2}},
+	}, 2)
+
+	allocation := Allocate(tx, artifact, nil, uint128.From64(3), &etched)
+	assert.Equal(t, []Balances{{}, {minted: uint128.From64(3), etched: uint128.From64(30)}, {etched: uint128.From64(20)}}, allocation.Outputs)
+	assert.Empty(t, allocation.Burned)
+
+	// without a valid etching, there is no premine and 0:0 edicts are skipped
+	allocation = Allocate(tx, artifact, nil, uint128.Zero, nil)
+	assert.Equal(t, []Balances{{}, {}, {}}, allocation.Outputs)
+
+	// mints of a cenotaph are burned
+	tx, artifact = allocationTx(t, &Runestone{Mint: &minted, Pointer: Uint32P(7)}, 1)
+	allocation = Allocate(tx, artifact, nil, uint128.From64(3), nil)
+	assert.Equal(t, Balances{minted: uint128.From64(3)}, allocation.Burned)
+}
@@ -15,8 +15,8 @@
 package runestone
 
 type Artifact struct {
-	Cenotaph  *Cenotaph
-	Runestone *Runestone
+	Cenotaph  *Cenotaph  `json:"cenotaph,omitempty"`
+	Runestone *Runestone `json:"runestone,omitempty"`
 }
 
 func (a *Artifact) Mint() *RuneId {
@@ -28,3 +28,17 @@ func (a *Artifact) Mint() *RuneId {
 	}
 	return nil
 }
+
+// EtchedRune returns the rune the artifact of the transaction at index tx of
+// the block at height block etches, or nil if it etches none. Whether the
+// etching succeeds, which for a named rune depends on its unlock height and
+// commitment, is up to the caller.
+func (a *Artifact) EtchedRune(block uint64, tx uint32) *Rune {
+	if a.Cenotaph != nil {
+		return a.Cenotaph.EtchedRune()
+	}
+	if a.Runestone != nil {
+		return a.Runestone.EtchedRune(block, tx)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TxArtifact is the artifact deciphered from the transaction at Index in a
+// block.
+type TxArtifact struct {
+	Index    uint32
+	Txid     chainhash.Hash
+	Artifact *Artifact
+}
+
+// EtchedRune returns the rune etched by the artifact of a, whose block is at
+// height.
+func (a TxArtifact) EtchedRune(height uint64) *Rune {
+	return a.Artifact.EtchedRune(height, a.Index)
+}
+
+// DecipherBlock deciphers every transaction of block on a worker pool of
+// GOMAXPROCS goroutines and returns the artifacts found, in transaction order.
+func DecipherBlock(block *wire.MsgBlock) []TxArtifact {
+	return DecipherTransactions(block.Transactions, runtime.GOMAXPROCS(0))
+}
+
+// DecipherBlock deciphers block like the DecipherBlock function, looking for
+// the magic number of c.
+func (c *Chain) DecipherBlock(block *wire.MsgBlock) []TxArtifact {
+	return decipherTransactions(block.Transactions, runtime.GOMAXPROCS(0), c.MagicNumber)
+}
+
+// DecipherTransactions deciphers txs on at most workers goroutines and returns
+// the artifacts found, in transaction order. Transactions without an
+// OP_RETURN MAGIC_NUMBER output are skipped without being deciphered or
+// hashed.
+func DecipherTransactions(txs []*wire.MsgTx, workers int) []TxArtifact {
+	return decipherTransactions(txs, workers, MAGIC_NUMBER)
+}
+
+func decipherTransactions(txs []*wire.MsgTx, workers int, magic byte) []TxArtifact {
+	var candidates []uint32
+	for i, tx := range txs {
+		if runestoneScript(tx, magic) != nil {
+			candidates = append(candidates, uint32(i))
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	results := make([]TxArtifact, len(candidates))
+	decipher := func(i int) {
+		tx := txs[candidates[i]]
+		artifact, _ := (&Runestone{}).decipher(tx, magic, nil)
+		results[i] = TxArtifact{Index: candidates[i], Txid: tx.TxHash(), Artifact: artifact}
+	}
+
+	workers = min(workers, len(candidates))
+	if workers <= 1 {
+		for i := range candidates {
+			decipher(i)
+		}
+	} else {
+		next := make(chan int, len(candidates))
+		for i := range candidates {
+			next <- i
+		}
+		close(next)
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					decipher(i)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	artifacts := results[:0]
+	for _, result := range results {
+		if result.Artifact != nil {
+			artifacts = append(artifacts, result)
+		}
+	}
+	return artifacts
+}
+
+// runestoneScript returns the first output script of tx that starts with
+// OP_RETURN magic, the one Decipher reads, or nil if there is none.
+func runestoneScript(tx *wire.MsgTx, magic byte) []byte {
+	for _, output := range tx.TxOut {
+		script := output.PkScript
+		if len(script) >= 2 && script[0] == txscript.OP_RETURN && script[1] == magic {
+			return script
+		}
+	}
+	return nil
+}
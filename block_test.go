@@ -0,0 +1,87 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"lukechampine.com/uint128"
+)
+
+// testBlock returns a block of n transactions, every tenth of which carries a
+// runestone and every hundredth an unrelated OP_RETURN.
+func testBlock(n int) *wire.MsgBlock {
+	block := &wire.MsgBlock{}
+	for i := 0; i < n; i++ {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: uint32(i)}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(1000, append([]byte{txscript.OP_0, txscript.OP_DATA_20}, make([]byte, 20)...)))
+		switch {
+		case i%10 == 0:
+			script, _ := (&Runestone{
+				Edicts: []Edict{{ID: RuneId{Block: 840000, Tx: uint32(i)}, Amount: uint128.From64(uint64(i)), Output: 0}},
+			}).Encipher()
+			tx.AddTxOut(wire.NewTxOut(0, script))
+		case i%100 == 1:
+			tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN, txscript.OP_DATA_1, 0x2a}))
+		}
+		block.Transactions = append(block.Transactions, tx)
+	}
+	return block
+}
+
+func TestDecipherBlock(t *testing.T) {
+	block := testBlock(1000)
+	// a cenotaph is still an artifact
+	block.Transactions[5].AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_VERIFY}))
+
+	var expected []TxArtifact
+	for i, tx := range block.Transactions {
+		artifact, _ := (&Runestone{}).Decipher(tx)
+		if artifact != nil {
+			expected = append(expected, TxArtifact{Index: uint32(i), Txid: tx.TxHash(), Artifact: artifact})
+		}
+	}
+	assert.Len(t, expected, 101)
+	assert.Equal(t, expected, DecipherBlock(block))
+	for _, workers := range []int{0, 1, 3, 64} {
+		assert.Equal(t, expected, DecipherTransactions(block.Transactions, workers), workers)
+	}
+	assert.Empty(t, DecipherBlock(&wire.MsgBlock{}))
+}
+
+func BenchmarkDecipherBlock(b *testing.B) {
+	block := testBlock(3000)
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				DecipherTransactions(block.Transactions, workers)
+			}
+		})
+	}
+	b.Run("sequential", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, tx := range block.Transactions {
+				(&Runestone{}).Decipher(tx)
+			}
+		}
+	})
+}
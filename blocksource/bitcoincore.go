@@ -0,0 +1,191 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocksource
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Error codes of Bitcoin Core meaning that a block or transaction is unknown.
+const (
+	rpcInvalidAddressOrKey = -5
+	rpcInvalidParameter    = -8
+)
+
+// BitcoinCore is a BlockSource backed by the JSON-RPC interface of a Bitcoin
+// Core node. RawTransaction only finds confirmed transactions when the node
+// runs with -txindex.
+type BitcoinCore struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+	id       atomic.Uint64
+}
+
+// NewBitcoinCore returns a source for the node answering JSON-RPC at url, such
+// as http://127.0.0.1:8332, authenticated with the rpcuser and rpcpassword of
+// its configuration.
+func NewBitcoinCore(url, user, password string) *BitcoinCore {
+	return &BitcoinCore{url: url, user: user, password: password, client: http.DefaultClient}
+}
+
+// RPCError is an error answered by the node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("bitcoin core error %d: %s", e.Code, e.Message)
+}
+
+// Is reports unknown blocks and transactions as ErrNotFound.
+func (e *RPCError) Is(target error) bool {
+	return target == ErrNotFound && e.Code == rpcInvalidAddressOrKey
+}
+
+func (c *BitcoinCore) TipHeight() (uint64, error) {
+	var height uint64
+	err := c.Call("getblockcount", &height)
+	return height, err
+}
+
+func (c *BitcoinCore) BlockHash(height uint64) (*chainhash.Hash, error) {
+	var hash string
+	if err := c.Call("getblockhash", &hash, height); err != nil {
+		if rpcErr, ok := err.(*RPCError); ok && rpcErr.Code == rpcInvalidParameter {
+			return nil, fmt.Errorf("block height %d: %w: %v", height, ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return chainhash.NewHashFromStr(hash)
+}
+
+func (c *BitcoinCore) Block(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	raw, err := c.rawHex("getblock", hash.String(), 0)
+	if err != nil {
+		return nil, err
+	}
+	block := &wire.MsgBlock{}
+	if err := block.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	if err := checkBlockHash(block, hash); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (c *BitcoinCore) RawTransaction(txid *chainhash.Hash) (*wire.MsgTx, error) {
+	raw, err := c.rawHex("getrawtransaction", txid.String())
+	if err != nil {
+		return nil, err
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Confirmations reads the confirmations of the verbose getrawtransaction.
+func (c *BitcoinCore) Confirmations(txid *chainhash.Hash) (uint64, error) {
+	var tx struct {
+		Confirmations uint64 `json:"confirmations"`
+	}
+	err := c.Call("getrawtransaction", &tx, txid.String(), true)
+	return tx.Confirmations, err
+}
+
+func (c *BitcoinCore) SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	var txid string
+	if err := c.Call("sendrawtransaction", &txid, hex.EncodeToString(buf.Bytes())); err != nil {
+		return nil, err
+	}
+	return chainhash.NewHashFromStr(txid)
+}
+
+// rawHex calls a method answering hex encoded bytes.
+func (c *BitcoinCore) rawHex(method string, params ...any) ([]byte, error) {
+	var res string
+	if err := c.Call(method, &res, params...); err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(res)
+}
+
+// Call calls method with params and decodes its result into result. Errors
+// answered by the node are returned as *RPCError.
+func (c *BitcoinCore) Call(method string, result any, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "1.0",
+		"id":      c.id.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// The node answers errors with a failure status and a JSON body, except
+	// for authentication failures, which have no body.
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.Unmarshal(res, &reply); err != nil {
+		return fmt.Errorf("%s: %s: %s", method, resp.Status, bytes.TrimSpace(res))
+	}
+	if reply.Error != nil {
+		return reply.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocksource
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Confirmer = (*BitcoinCore)(nil)
+
+func TestBitcoinCore(t *testing.T) {
+	blocks := testBlocks(2)
+	tip := blocks[1]
+	tx := tip.Transactions[0]
+	var broadcast string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		reply := map[string]any{"id": req.ID, "error": nil}
+		switch req.Method {
+		case "getblockcount":
+			reply["result"] = 1
+		case "getblockhash":
+			if req.Params[0] != 1.0 {
+				reply["error"] = RPCError{Code: rpcInvalidParameter, Message: "Block height out of range"}
+				break
+			}
+			reply["result"] = tip.BlockHash().String()
+		case "getblock":
+			assert.Equal(t, []any{tip.BlockHash().String(), 0.0}, req.Params)
+			reply["result"] = hex.EncodeToString(serialize(t, tip))
+		case "getrawtransaction":
+			if req.Params[0] != tx.TxHash().String() {
+				reply["error"] = RPCError{Code: rpcInvalidAddressOrKey, Message: "No such mempool or blockchain transaction"}
+				break
+			}
+			if len(req.Params) > 1 && req.Params[1] == true {
+				reply["result"] = map[string]any{"txid": tx.TxHash().String(), "confirmations": 3}
+				break
+			}
+			reply["result"] = hex.EncodeToString(serialize(t, tx))
+		case "sendrawtransaction":
+			broadcast = req.Params[0].(string)
+			reply["result"] = tx.TxHash().String()
+		}
+		if reply["error"] != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(reply)
+	}))
+	defer server.Close()
+	node := NewBitcoinCore(server.URL, "user", "pass")
+
+	height, err := node.TipHeight()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), height)
+	hash, err := node.BlockHash(1)
+	require.NoError(t, err)
+	assert.Equal(t, tip.BlockHash(), *hash)
+	block, err := node.Block(hash)
+	require.NoError(t, err)
+	assert.Equal(t, tip, block)
+	txid := tx.TxHash()
+	got, err := node.RawTransaction(&txid)
+	require.NoError(t, err)
+	assert.Equal(t, tx, got)
+	sent, err := node.SendRawTransaction(tx)
+	require.NoError(t, err)
+	assert.Equal(t, txid, *sent)
+	assert.Equal(t, hex.EncodeToString(serialize(t, tx)), broadcast)
+
+	confirmations, err := node.Confirmations(&txid)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), confirmations)
+
+	_, err = node.BlockHash(2)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = node.RawTransaction(hash)
+	assert.ErrorIs(t, err, ErrNotFound)
+	var rpcErr *RPCError
+	assert.ErrorAs(t, err, &rpcErr)
+
+	_, err = NewBitcoinCore(server.URL, "user", "wrong").TipHeight()
+	assert.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,207 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocksource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// blockExt is the extension of block files in a BlockDir.
+const blockExt = ".blk"
+
+// BlockDir is a BlockSource reading a directory with one raw serialized block
+// per file, named by its height, such as 840000.blk. It suits tests and
+// offline indexing of blocks exported from a node. The best chain is the one
+// in the directory, so there is nothing to broadcast to: SendRawTransaction
+// returns errors.ErrUnsupported.
+type BlockDir struct {
+	dir string
+
+	mu      sync.Mutex
+	heights map[chainhash.Hash]uint64
+}
+
+// NewBlockDir returns a source reading the blocks in dir.
+func NewBlockDir(dir string) *BlockDir {
+	return &BlockDir{dir: dir, heights: make(map[chainhash.Hash]uint64)}
+}
+
+// WriteBlock stores block as the block at height.
+func (d *BlockDir) WriteBlock(height uint64, block *wire.MsgBlock) error {
+	var buf bytes.Buffer
+	if err := block.Serialize(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(d.path(height), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.heights[block.BlockHash()] = height
+	d.mu.Unlock()
+	return nil
+}
+
+// TipHeight returns the highest height in the directory.
+func (d *BlockDir) TipHeight() (uint64, error) {
+	heights, err := d.list()
+	if err != nil {
+		return 0, err
+	}
+	if len(heights) == 0 {
+		return 0, fmt.Errorf("no blocks in %s: %w", d.dir, ErrNotFound)
+	}
+	tip := heights[0]
+	for _, height := range heights[1:] {
+		tip = max(tip, height)
+	}
+	return tip, nil
+}
+
+func (d *BlockDir) BlockHash(height uint64) (*chainhash.Hash, error) {
+	f, err := os.Open(d.path(height))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("block height %d: %w", height, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var header wire.BlockHeader
+	if err := header.Deserialize(f); err != nil {
+		return nil, fmt.Errorf("block height %d: %w", height, err)
+	}
+	hash := header.BlockHash()
+	d.mu.Lock()
+	d.heights[hash] = height
+	d.mu.Unlock()
+	return &hash, nil
+}
+
+func (d *BlockDir) Block(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	d.mu.Lock()
+	height, ok := d.heights[*hash]
+	d.mu.Unlock()
+	if !ok {
+		// Learn the hashes of files added since the last lookup.
+		heights, err := d.list()
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range heights {
+			got, err := d.BlockHash(h)
+			if err != nil {
+				return nil, err
+			}
+			if *got == *hash {
+				height, ok = h, true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("block %s: %w", hash, ErrNotFound)
+		}
+	}
+	block, err := d.read(height)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlockHash(block, hash); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// RawTransaction searches the blocks from the tip down, so it reads the whole
+// directory for a transaction that is not there.
+func (d *BlockDir) RawTransaction(txid *chainhash.Hash) (*wire.MsgTx, error) {
+	tip, err := d.TipHeight()
+	if err != nil {
+		return nil, err
+	}
+	for height := int64(tip); height >= 0; height-- {
+		block, err := d.read(uint64(height))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range block.Transactions {
+			if tx.TxHash() == *txid {
+				return tx, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("tx %s: %w", txid, ErrNotFound)
+}
+
+func (d *BlockDir) SendRawTransaction(*wire.MsgTx) (*chainhash.Hash, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (d *BlockDir) path(height uint64) string {
+	return filepath.Join(d.dir, strconv.FormatUint(height, 10)+blockExt)
+}
+
+func (d *BlockDir) read(height uint64) (*wire.MsgBlock, error) {
+	f, err := os.Open(d.path(height))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("block height %d: %w", height, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	block := &wire.MsgBlock{}
+	if err := block.Deserialize(f); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("truncated block file: %w", err)
+		}
+		return nil, fmt.Errorf("block height %d: %w", height, err)
+	}
+	return block, nil
+}
+
+// list returns the heights of the block files in the directory.
+func (d *BlockDir) list() ([]uint64, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	var heights []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), blockExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		height, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		heights = append(heights, height)
+	}
+	return heights, nil
+}
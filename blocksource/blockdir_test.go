@@ -0,0 +1,96 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocksource
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/bxelab/runestone/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBlocks returns a chain of n blocks holding a coinbase each.
+func testBlocks(n int) []*wire.MsgBlock {
+	blocks := make([]*wire.MsgBlock, n)
+	for height := range blocks {
+		coinbase := wire.NewMsgTx(wire.TxVersion)
+		coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex}, []byte{byte(height), 0}, nil))
+		coinbase.AddTxOut(wire.NewTxOut(50, []byte{txscript.OP_TRUE}))
+		block := &wire.MsgBlock{
+			Header:       wire.BlockHeader{Timestamp: time.Unix(int64(height), 0)},
+			Transactions: []*wire.MsgTx{coinbase},
+		}
+		if height > 0 {
+			block.Header.PrevBlock = blocks[height-1].BlockHash()
+		}
+		blocks[height] = block
+	}
+	return blocks
+}
+
+func TestBlockDir(t *testing.T) {
+	blocks := testBlocks(3)
+	dir := NewBlockDir(t.TempDir())
+	_, err := dir.TipHeight()
+	assert.ErrorIs(t, err, ErrNotFound)
+	for height, block := range blocks {
+		require.NoError(t, dir.WriteBlock(uint64(height), block))
+	}
+
+	// A fresh source learns the hashes from the files.
+	dir = NewBlockDir(dir.dir)
+	tip, err := dir.TipHeight()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), tip)
+	hash := blocks[2].BlockHash()
+	block, err := dir.Block(&hash)
+	require.NoError(t, err)
+	assert.Equal(t, blocks[2], block)
+	got, err := dir.BlockHash(1)
+	require.NoError(t, err)
+	assert.Equal(t, blocks[1].BlockHash(), *got)
+
+	txid := blocks[0].Transactions[0].TxHash()
+	tx, err := dir.RawTransaction(&txid)
+	require.NoError(t, err)
+	assert.Equal(t, blocks[0].Transactions[0], tx)
+
+	_, err = dir.BlockHash(3)
+	assert.ErrorIs(t, err, ErrNotFound)
+	unknown := blocks[0].Header.PrevBlock
+	_, err = dir.Block(&unknown)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = dir.RawTransaction(&unknown)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = dir.SendRawTransaction(tx)
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+}
+
+func TestIndexSyncsFromBlockDir(t *testing.T) {
+	dir := NewBlockDir(t.TempDir())
+	for height, block := range testBlocks(3) {
+		require.NoError(t, dir.WriteBlock(uint64(height), block))
+	}
+	idx, err := index.NewIndex(index.NewMemoryStore(), runestone.Regtest, nil)
+	require.NoError(t, err)
+	require.NoError(t, idx.Sync(dir))
+	assert.Equal(t, uint64(3), idx.NextHeight())
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package blocksource provides the chain data that indexing and tooling need
+// from a Bitcoin backend: an Esplora REST API such as mempool.space, a Bitcoin
+// Core node over JSON-RPC, or a local directory of raw blocks.
+package blocksource
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BlockSource reads blocks and transactions from a backend and broadcasts
+// transactions to it. It satisfies index.Chain, so an index can Sync from it,
+// and httpapi.TxSource.
+type BlockSource interface {
+	// TipHeight returns the height of the best block.
+	TipHeight() (uint64, error)
+	// BlockHash returns the hash of the block at height in the best chain.
+	BlockHash(height uint64) (*chainhash.Hash, error)
+	Block(hash *chainhash.Hash) (*wire.MsgBlock, error)
+	RawTransaction(txid *chainhash.Hash) (*wire.MsgTx, error)
+	// SendRawTransaction broadcasts tx and returns its txid.
+	SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error)
+}
+
+// Confirmer is implemented by sources that can tell how many confirmations a
+// transaction has without reading blocks.
+type Confirmer interface {
+	// Confirmations returns the confirmations of txid, 0 while it is in the
+	// mempool.
+	Confirmations(txid *chainhash.Hash) (uint64, error)
+}
+
+// ErrNotFound is returned, possibly wrapped, for blocks, heights and
+// transactions the backend does not know.
+var ErrNotFound = errors.New("not found")
+
+// checkBlockHash checks that a block fetched by hash is that block.
+func checkBlockHash(block *wire.MsgBlock, hash *chainhash.Hash) error {
+	if got := block.BlockHash(); got != *hash {
+		return errors.New("block " + hash.String() + " has hash " + got.String())
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocksource
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Esplora is a BlockSource backed by the REST API of Esplora or mempool.space,
+// for example https://mempool.space/api.
+type Esplora struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewEsplora returns an Esplora source for the API at baseURL.
+func NewEsplora(baseURL string) *Esplora {
+	return &Esplora{baseURL: strings.TrimSuffix(baseURL, "/"), client: http.DefaultClient}
+}
+
+func (e *Esplora) TipHeight() (uint64, error) {
+	res, err := e.text(http.MethodGet, "/blocks/tip/height", nil)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(res, 10, 64)
+}
+
+func (e *Esplora) BlockHash(height uint64) (*chainhash.Hash, error) {
+	res, err := e.text(http.MethodGet, fmt.Sprintf("/block-height/%d", height), nil)
+	if err != nil {
+		return nil, err
+	}
+	return chainhash.NewHashFromStr(res)
+}
+
+func (e *Esplora) Block(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	res, err := e.Request(http.MethodGet, fmt.Sprintf("/block/%s/raw", hash), nil)
+	if err != nil {
+		return nil, err
+	}
+	block := &wire.MsgBlock{}
+	if err := block.Deserialize(bytes.NewReader(res)); err != nil {
+		return nil, err
+	}
+	if err := checkBlockHash(block, hash); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (e *Esplora) RawTransaction(txid *chainhash.Hash) (*wire.MsgTx, error) {
+	res, err := e.Request(http.MethodGet, fmt.Sprintf("/tx/%s/raw", txid), nil)
+	if err != nil {
+		return nil, err
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(res)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Confirmations asks /tx/:txid/status whether and where txid is confirmed.
+func (e *Esplora) Confirmations(txid *chainhash.Hash) (uint64, error) {
+	res, err := e.Request(http.MethodGet, fmt.Sprintf("/tx/%s/status", txid), nil)
+	if err != nil {
+		return 0, err
+	}
+	var status struct {
+		Confirmed   bool   `json:"confirmed"`
+		BlockHeight uint64 `json:"block_height"`
+	}
+	if err := json.Unmarshal(res, &status); err != nil {
+		return 0, err
+	}
+	if !status.Confirmed {
+		return 0, nil
+	}
+	tip, err := e.TipHeight()
+	if err != nil {
+		return 0, err
+	}
+	if tip < status.BlockHeight {
+		return 1, nil
+	}
+	return tip - status.BlockHeight + 1, nil
+}
+
+func (e *Esplora) SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	res, err := e.text(http.MethodPost, "/tx", strings.NewReader(hex.EncodeToString(buf.Bytes())))
+	if err != nil {
+		return nil, err
+	}
+	txid, err := chainhash.NewHashFromStr(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse txid %q: %w", res, err)
+	}
+	return txid, nil
+}
+
+// Request sends a request to the API path, such as "/address/{address}/utxo",
+// and returns the response body. A 404 status is returned as ErrNotFound and
+// any other failure status as an error holding the body, which is where
+// Esplora explains it.
+func (e *Esplora) Request(method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, e.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s %s: %w: %s", method, path, ErrNotFound, bytes.TrimSpace(res))
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(res))
+	}
+	return res, nil
+}
+
+// text sends a request answered by a line of text, such as a hash or height.
+func (e *Esplora) text(method, path string, body io.Reader) (string, error) {
+	res, err := e.Request(method, path, body)
+	return strings.TrimSpace(string(res)), err
+}
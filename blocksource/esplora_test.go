@@ -0,0 +1,107 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocksource
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Confirmer = (*Esplora)(nil)
+
+func serialize(t *testing.T, v interface{ Serialize(io.Writer) error }) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, v.Serialize(&buf))
+	return buf.Bytes()
+}
+
+func TestEsplora(t *testing.T) {
+	blocks := testBlocks(2)
+	tip := blocks[1]
+	tx := tip.Transactions[0]
+	var broadcast []byte
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1")
+	})
+	mux.HandleFunc("GET /block-height/{height}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("height") != "1" {
+			http.Error(w, "Block not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, tip.BlockHash())
+	})
+	mux.HandleFunc("GET /block/{hash}/raw", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(serialize(t, tip))
+	})
+	mux.HandleFunc("GET /tx/{txid}/raw", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(serialize(t, tx))
+	})
+	mux.HandleFunc("GET /tx/{txid}/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("txid") != tx.TxHash().String() {
+			fmt.Fprint(w, `{"confirmed":false}`)
+			return
+		}
+		fmt.Fprint(w, `{"confirmed":true,"block_height":1,"block_hash":"`+tip.BlockHash().String()+`"}`)
+	})
+	mux.HandleFunc("POST /tx", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		broadcast, _ = hex.DecodeString(string(body))
+		fmt.Fprint(w, tx.TxHash())
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	esplora := NewEsplora(server.URL + "/")
+
+	height, err := esplora.TipHeight()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), height)
+	hash, err := esplora.BlockHash(1)
+	require.NoError(t, err)
+	assert.Equal(t, tip.BlockHash(), *hash)
+	block, err := esplora.Block(hash)
+	require.NoError(t, err)
+	assert.Equal(t, tip, block)
+	txid := tx.TxHash()
+	got, err := esplora.RawTransaction(&txid)
+	require.NoError(t, err)
+	assert.Equal(t, tx, got)
+	sent, err := esplora.SendRawTransaction(tx)
+	require.NoError(t, err)
+	assert.Equal(t, txid, *sent)
+	assert.Equal(t, serialize(t, tx), broadcast)
+
+	confirmations, err := esplora.Confirmations(&txid)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), confirmations)
+	confirmations, err = esplora.Confirmations(hash)
+	require.NoError(t, err)
+	assert.Zero(t, confirmations)
+
+	_, err = esplora.BlockHash(2)
+	assert.ErrorIs(t, err, ErrNotFound)
+	// The server answers every hash with the tip.
+	other := blocks[0].BlockHash()
+	_, err = esplora.Block(&other)
+	assert.Error(t, err)
+}
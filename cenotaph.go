@@ -15,7 +15,14 @@
 package runestone
 
 type Cenotaph struct {
-	Etching *Rune
-	Flaw    *Flaw
-	Mint    *RuneId
+	Etching *Rune   `json:"etching"`
+	Flaw    *Flaw   `json:"flaw"`
+	Mint    *RuneId `json:"mint"`
+}
+
+// EtchedRune returns the rune that c etches, which is the one named by its
+// etching. As in ord, a cenotaph whose etching has no name etches nothing, so
+// unlike for a runestone no reserved rune is assigned.
+func (c *Cenotaph) EtchedRune() *Rune {
+	return c.Etching
 }
@@ -0,0 +1,220 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	ErrUnknownChain   = errors.New("unknown chain")
+	ErrDuplicateChain = errors.New("chain already registered")
+	ErrInvalidChain   = errors.New("invalid chain")
+)
+
+// Chain holds the parameters of a network that the rune protocol depends on,
+// together with the btcd parameters used to encode its addresses.
+type Chain struct {
+	Name   string
+	Params *chaincfg.Params
+
+	// FirstRuneHeight is the height from which runes can be etched, and
+	// from which name lengths unlock over SubsidyHalvingInterval blocks.
+	FirstRuneHeight        uint64
+	SubsidyHalvingInterval uint64
+	// MagicNumber is the opcode following OP_RETURN in runestone outputs.
+	MagicNumber byte
+	// CommitConfirmations is the number of confirmations the output
+	// committing to a rune name needs before the etching can reveal it.
+	CommitConfirmations uint64
+}
+
+// The chains ord supports. Like ord, every one of them unlocks rune names
+// over a mainnet halving interval of blocks.
+var (
+	Mainnet = &Chain{
+		Name:                   "mainnet",
+		Params:                 &chaincfg.MainNetParams,
+		FirstRuneHeight:        uint64(SUBSIDY_HALVING_INTERVAL) * 4,
+		SubsidyHalvingInterval: uint64(SUBSIDY_HALVING_INTERVAL),
+		MagicNumber:            MAGIC_NUMBER,
+		CommitConfirmations:    COMMIT_CONFIRMATIONS,
+	}
+	Testnet3 = &Chain{
+		Name:                   "testnet3",
+		Params:                 &chaincfg.TestNet3Params,
+		FirstRuneHeight:        uint64(SUBSIDY_HALVING_INTERVAL) * 12,
+		SubsidyHalvingInterval: uint64(SUBSIDY_HALVING_INTERVAL),
+		MagicNumber:            MAGIC_NUMBER,
+		CommitConfirmations:    COMMIT_CONFIRMATIONS,
+	}
+	Testnet4 = &Chain{
+		Name:                   "testnet4",
+		Params:                 &testNet4Params,
+		SubsidyHalvingInterval: uint64(SUBSIDY_HALVING_INTERVAL),
+		MagicNumber:            MAGIC_NUMBER,
+		CommitConfirmations:    COMMIT_CONFIRMATIONS,
+	}
+	Signet = &Chain{
+		Name:                   "signet",
+		Params:                 &chaincfg.SigNetParams,
+		SubsidyHalvingInterval: uint64(SUBSIDY_HALVING_INTERVAL),
+		MagicNumber:            MAGIC_NUMBER,
+		CommitConfirmations:    COMMIT_CONFIRMATIONS,
+	}
+	Regtest = &Chain{
+		Name:                   "regtest",
+		Params:                 &chaincfg.RegressionNetParams,
+		SubsidyHalvingInterval: uint64(SUBSIDY_HALVING_INTERVAL),
+		MagicNumber:            MAGIC_NUMBER,
+		CommitConfirmations:    COMMIT_CONFIRMATIONS,
+	}
+)
+
+// testNet4Net is the network magic of testnet4.
+const testNet4Net wire.BitcoinNet = 0x283f161c
+
+// testNet4Params are the parameters of testnet4 (BIP 94), which the btcd
+// version in use does not define. Its addresses are encoded as on testnet3.
+var testNet4Params = func() chaincfg.Params {
+	params := chaincfg.TestNet3Params
+	params.Name = "testnet4"
+	params.Net = testNet4Net
+	params.DefaultPort = "48333"
+	params.DNSSeeds = nil
+	params.Checkpoints = nil
+	params.GenesisBlock = nil
+	params.GenesisHash, _ = chainhash.NewHashFromStr("00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043")
+	return params
+}()
+
+var registry = struct {
+	sync.RWMutex
+	chains  []*Chain
+	aliases map[string]string
+}{
+	chains:  []*Chain{Mainnet, Testnet3, Testnet4, Signet, Regtest},
+	aliases: map[string]string{"testnet": "testnet3"},
+}
+
+// RegisterChain makes a custom chain, such as a private signet, available to
+// ChainByName and ChainByNet. Its name and network magic must not be those of
+// a chain already registered, and its halving interval must be at least 12
+// blocks.
+func RegisterChain(chain *Chain) error {
+	if chain.Name == "" || chain.Params == nil {
+		return fmt.Errorf("%w: %q needs a name and params", ErrInvalidChain, chain.Name)
+	}
+	// names unlock one length every twelfth of the interval
+	if chain.SubsidyHalvingInterval < 12 {
+		return fmt.Errorf("%w: %q needs a halving interval of at least 12 blocks", ErrInvalidChain, chain.Name)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	for _, c := range registry.chains {
+		if c.Name == chain.Name || c.Params.Net == chain.Params.Net {
+			return fmt.Errorf("%w: %s", ErrDuplicateChain, chain.Name)
+		}
+	}
+	if _, ok := registry.aliases[chain.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateChain, chain.Name)
+	}
+	registry.chains = append(registry.chains, chain)
+	return nil
+}
+
+// ChainByName returns the registered chain called name. "testnet" is
+// accepted for testnet3, as in ord.
+func ChainByName(name string) (*Chain, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if alias, ok := registry.aliases[name]; ok {
+		name = alias
+	}
+	for _, c := range registry.chains {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownChain, name)
+}
+
+// ChainByNet returns the registered chain whose network magic is net.
+func ChainByNet(net wire.BitcoinNet) (*Chain, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, c := range registry.chains {
+		if c.Params.Net == net {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownChain, net)
+}
+
+// Chains returns the registered chains, built-in ones first.
+func Chains() []*Chain {
+	registry.RLock()
+	defer registry.RUnlock()
+	return append([]*Chain(nil), registry.chains...)
+}
+
+func (c *Chain) String() string {
+	return c.Name
+}
+
+// MinimumAtHeight returns the smallest rune that can be etched in the block
+// at height. Before FirstRuneHeight only names of thirteen letters or more
+// can be etched; one length more unlocks every twelfth of a halving interval
+// after it, down to single letters.
+func (c *Chain) MinimumAtHeight(height uint64) Rune {
+	offset := height + 1
+	// a chain built without RegisterChain may have an interval below 12
+	interval := max(c.SubsidyHalvingInterval/12, 1)
+	start := c.FirstRuneHeight
+	end := start + c.SubsidyHalvingInterval
+	if offset < start {
+		return Rune{STEPS[12]}
+	}
+	progress := offset - start
+	if offset >= end || progress/interval >= 12 {
+		return Rune{}
+	}
+	length := 12 - progress/interval
+	endStep := STEPS[length-1]
+	startStep := STEPS[length]
+	remainder := progress % interval
+
+	//val := startStep - ((startStep - endStep) * remainder / interval)
+	val := startStep.Sub(startStep.Sub(endStep).Mul64(remainder).Div64(interval))
+	return Rune{val}
+}
+
+// Decipher deciphers tx like Runestone.Decipher, looking for the magic number
+// of c.
+func (c *Chain) Decipher(tx *wire.MsgTx) (*Artifact, error) {
+	return (&Runestone{}).decipher(tx, c.MagicNumber, nil)
+}
+
+// Encipher returns the OP_RETURN script of r like Runestone.Encipher, with
+// the magic number of c.
+func (c *Chain) Encipher(r *Runestone) ([]byte, error) {
+	return encipherScript(r.encipherPayload(), c.MagicNumber), nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainByName(t *testing.T) {
+	for _, chain := range []*Chain{Mainnet, Testnet3, Testnet4, Signet, Regtest} {
+		c, err := ChainByName(chain.Name)
+		require.NoError(t, err)
+		assert.Same(t, chain, c)
+		c, err = ChainByNet(chain.Params.Net)
+		require.NoError(t, err)
+		assert.Same(t, chain, c)
+	}
+	c, err := ChainByName("testnet")
+	require.NoError(t, err)
+	assert.Same(t, Testnet3, c)
+
+	_, err = ChainByName("simnet")
+	assert.ErrorIs(t, err, ErrUnknownChain)
+	_, err = ChainByNet(wire.SimNet)
+	assert.ErrorIs(t, err, ErrUnknownChain)
+
+	assert.Equal(t, uint64(840000), Mainnet.FirstRuneHeight)
+	assert.Equal(t, uint64(2520000), Testnet3.FirstRuneHeight)
+	assert.Zero(t, Testnet4.FirstRuneHeight)
+	assert.Equal(t, "tb", Testnet4.Params.Bech32HRPSegwit)
+	assert.NotEqual(t, Testnet3.Params.Net, Testnet4.Params.Net)
+}
+
+func TestRegisterChain(t *testing.T) {
+	params := chaincfg.CustomSignetParams([]byte{txscript.OP_TRUE}, nil)
+	custom := &Chain{
+		Name:                   "customsignet",
+		Params:                 &params,
+		FirstRuneHeight:        100,
+		SubsidyHalvingInterval: 150,
+		MagicNumber:            txscript.OP_14,
+		CommitConfirmations:    1,
+	}
+	require.NoError(t, RegisterChain(custom))
+	c, err := ChainByName("customsignet")
+	require.NoError(t, err)
+	assert.Same(t, custom, c)
+	c, err = ChainByNet(params.Net)
+	require.NoError(t, err)
+	assert.Same(t, custom, c)
+	assert.Contains(t, Chains(), custom)
+
+	assert.ErrorIs(t, RegisterChain(custom), ErrDuplicateChain)
+	assert.ErrorIs(t, RegisterChain(&Chain{Name: "testnet", Params: &chaincfg.SimNetParams, SubsidyHalvingInterval: 12}), ErrDuplicateChain)
+	assert.ErrorIs(t, RegisterChain(&Chain{Name: "signet2", Params: &chaincfg.SigNetParams, SubsidyHalvingInterval: 12}), ErrDuplicateChain)
+	assert.ErrorIs(t, RegisterChain(&Chain{Name: "empty"}), ErrInvalidChain)
+	shortParams := chaincfg.CustomSignetParams([]byte{txscript.OP_FALSE}, nil)
+	short := &Chain{Name: "short", Params: &shortParams, FirstRuneHeight: 10, SubsidyHalvingInterval: 11}
+	assert.ErrorIs(t, RegisterChain(short), ErrInvalidChain)
+	// an unregistered chain with a short interval unlocks a length per block
+	assert.Equal(t, STEPS[12], short.MinimumAtHeight(9).Value)
+	assert.Equal(t, STEPS[11], short.MinimumAtHeight(10).Value)
+	assert.Equal(t, STEPS[2], short.MinimumAtHeight(19).Value)
+	assert.Equal(t, Rune{}, short.MinimumAtHeight(20))
+
+	// names unlock over the halving interval of the chain, 12 blocks per length
+	assert.Equal(t, STEPS[12], custom.MinimumAtHeight(98).Value)
+	assert.Equal(t, STEPS[11], custom.MinimumAtHeight(111).Value)
+	assert.Equal(t, STEPS[0], custom.MinimumAtHeight(243).Value)
+	height, err := custom.NameLengthUnlockHeight(1)
+	require.NoError(t, err)
+	assert.Less(t, height, uint64(250))
+
+	// the magic number of the chain marks its runestones
+	r := &Runestone{Pointer: Uint32P(0)}
+	script, err := custom.Encipher(r)
+	require.NoError(t, err)
+	assert.Equal(t, byte(txscript.OP_14), script[1])
+	tx := &wire.MsgTx{TxOut: []*wire.TxOut{{PkScript: script}}}
+	artifact, err := custom.Decipher(tx)
+	require.NoError(t, err)
+	assert.Equal(t, r, artifact.Runestone)
+	_, err = (&Runestone{}).Decipher(tx)
+	assert.Error(t, err)
+	assert.Len(t, custom.DecipherBlock(&wire.MsgBlock{Transactions: []*wire.MsgTx{tx}}), 1)
+	assert.Empty(t, DecipherBlock(&wire.MsgBlock{Transactions: []*wire.MsgTx{tx}}))
+}
+
+func TestDeprecatedNetworkFunctions(t *testing.T) {
+	assert.Equal(t, uint32(840000), FirstRuneHeight(wire.MainNet))
+	assert.Equal(t, uint32(0), FirstRuneHeight(wire.SimNet))
+	assert.Equal(t, Mainnet.MinimumAtHeight(850000), MinimumAtHeight(wire.MainNet, 850000))
+	assert.Equal(t, Signet.MinimumAtHeight(1000), MinimumAtHeight(chaincfg.SigNetParams.Net, 1000))
+}
@@ -13,21 +13,27 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/bxelab/runestone"
+	"github.com/bxelab/runestone/blocksource"
+	"github.com/bxelab/runestone/index"
 	"lukechampine.com/uint128"
 )
 
 type Config struct {
 	PrivateKey string
+	PublicKey  string
 	FeePerByte int64
 	UtxoAmount int64
 	Network    string
 	RpcUrl     string
+	IndexPath  string
 	Etching    *struct {
 		Rune              string
 		Logo              string
 		Symbol            *string
-		Premine           *uint64
-		Amount            *uint64
+		Premine           *uint64 // in base units
+		PremineDecimal    *string // in whole runes, instead of Premine
+		Amount            *uint64 // in base units
+		AmountDecimal     *string // in whole runes, instead of Amount
 		Cap               *uint64
 		Divisibility      *int
 		HeightStart       *int
@@ -38,6 +44,15 @@ type Config struct {
 	Mint *struct {
 		RuneId string
 	}
+	// BlockSource is the backend for the chain tip and broadcasting, the
+	// Esplora API at RpcUrl if not set. UTXOs always come from RpcUrl.
+	BlockSource *struct {
+		Type     string // esplora, bitcoind or blockdir
+		Url      string
+		User     string
+		Password string
+		Dir      string
+	}
 }
 
 func DefaultConfig() Config {
@@ -87,24 +102,31 @@ func (c Config) GetEtching() (*runestone.Etching, error) {
 		symbol := ([]rune(symbolStr))[0]
 		etching.Symbol = &symbol
 	}
-	if c.Etching.Premine != nil {
-		premine := uint128.From64(*c.Etching.Premine)
-		etching.Premine = &premine
+	divisibility := uint8(0)
+	if c.Etching.Divisibility != nil {
+		divisibility = uint8(*c.Etching.Divisibility)
 	}
-	if c.Etching.Amount != nil {
-		amount := uint128.From64(*c.Etching.Amount)
+	premine, err := etchingAmount(c.Etching.Premine, c.Etching.PremineDecimal, divisibility, "Premine")
+	if err != nil {
+		return nil, err
+	}
+	etching.Premine = premine
+	amount, err := etchingAmount(c.Etching.Amount, c.Etching.AmountDecimal, divisibility, "Amount")
+	if err != nil {
+		return nil, err
+	}
+	if amount != nil {
 		if etching.Terms == nil {
 			etching.Terms = &runestone.Terms{}
 		}
-		etching.Terms.Amount = &amount
+		etching.Terms.Amount = amount
 	}
 	if c.Etching.Cap != nil {
 		cap := uint128.From64(*c.Etching.Cap)
 		etching.Terms.Cap = &cap
 	}
 	if c.Etching.Divisibility != nil {
-		d := uint8(*c.Etching.Divisibility)
-		etching.Divisibility = &d
+		etching.Divisibility = &divisibility
 	}
 	if c.Etching.HeightStart != nil {
 		h := uint64(*c.Etching.HeightStart)
@@ -136,6 +158,31 @@ func (c Config) GetEtching() (*runestone.Etching, error) {
 	}
 	return etching, nil
 }
+
+// etchingAmount returns the amount configured under key, either in base units
+// or, under key+"Decimal", in whole runes such as "1234.5678", which are
+// converted to base units of a rune with the given divisibility.
+func etchingAmount(baseUnits *uint64, decimal *string, divisibility uint8, key string) (*uint128.Uint128, error) {
+	if baseUnits != nil && decimal != nil {
+		return nil, errors.New(key + " and " + key + "Decimal cannot both be set")
+	}
+	if baseUnits != nil {
+		amount := uint128.From64(*baseUnits)
+		return &amount, nil
+	}
+	if decimal == nil {
+		return nil, nil
+	}
+	d, err := runestone.ParseDecimal(*decimal)
+	if err != nil {
+		return nil, errors.New(key + "Decimal: " + err.Error())
+	}
+	amount, err := d.Integer(divisibility)
+	if err != nil {
+		return nil, errors.New(key + "Decimal: " + err.Error())
+	}
+	return &amount, nil
+}
 func (c Config) GetMint() (*runestone.RuneId, error) {
 	if c.Mint == nil {
 		return nil, errors.New("Mint config is required")
@@ -149,20 +196,15 @@ func (c Config) GetMint() (*runestone.RuneId, error) {
 	}
 	return runeId, nil
 }
-func (c Config) GetNetwork() *chaincfg.Params {
-	if c.Network == "mainnet" {
-		return &chaincfg.MainNetParams
-	}
-	if c.Network == "testnet" {
-		return &chaincfg.TestNet3Params
-	}
-	if c.Network == "regtest" {
-		return &chaincfg.RegressionNetParams
-	}
-	if c.Network == "signet" {
-		return &chaincfg.SigNetParams
+func (c Config) GetChain() *runestone.Chain {
+	chain, err := runestone.ChainByName(c.Network)
+	if err != nil {
+		panic(err)
 	}
-	panic("unknown network")
+	return chain
+}
+func (c Config) GetNetwork() *chaincfg.Params {
+	return c.GetChain().Params
 }
 
 func (c Config) GetPrivateKeyAddr() (*btcec.PrivateKey, string, error) {
@@ -187,6 +229,76 @@ func (c Config) GetPrivateKeyAddr() (*btcec.PrivateKey, string, error) {
 	address := addr.EncodeAddress()
 	return privKey, address, nil
 }
+
+// GetPublicKeyAddr returns the public key and taproot address of the wallet.
+// The key comes from PrivateKey when it is set, otherwise from PublicKey, so
+// that PSBTs can be built for a wallet that signs elsewhere.
+func (c Config) GetPublicKeyAddr() (*btcec.PublicKey, string, error) {
+	if c.PrivateKey != "" {
+		privKey, address, err := c.GetPrivateKeyAddr()
+		if err != nil {
+			return nil, "", err
+		}
+		return privKey.PubKey(), address, nil
+	}
+	if c.PublicKey == "" {
+		return nil, "", errors.New("PrivateKey or PublicKey is required")
+	}
+	pkBytes, err := hex.DecodeString(c.PublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+	var pubKey *btcec.PublicKey
+	if len(pkBytes) == schnorr.PubKeyBytesLen {
+		pubKey, err = schnorr.ParsePubKey(pkBytes)
+	} else {
+		pubKey, err = btcec.ParsePubKey(pkBytes)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	address, err := GetP2TRAddress(pubKey, c.GetNetwork())
+	if err != nil {
+		return nil, "", err
+	}
+	return pubKey, address, nil
+}
+
+// OpenIndex opens the rune index kept at IndexPath.
+func (c Config) OpenIndex() (*index.Index, func() error, error) {
+	if c.IndexPath == "" {
+		return nil, nil, errors.New("IndexPath is required")
+	}
+	store, err := index.OpenBoltStore(c.IndexPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	idx, err := index.NewIndex(store, c.GetChain(), nil)
+	if err != nil {
+		store.Close()
+		return nil, nil, err
+	}
+	return idx, store.Close, nil
+}
+
+// GetBlockSource returns the configured chain backend.
+func (c Config) GetBlockSource() (blocksource.BlockSource, error) {
+	if c.BlockSource == nil {
+		return blocksource.NewEsplora(c.RpcUrl), nil
+	}
+	switch c.BlockSource.Type {
+	case "", "esplora":
+		return blocksource.NewEsplora(c.BlockSource.Url), nil
+	case "bitcoind":
+		return blocksource.NewBitcoinCore(c.BlockSource.Url, c.BlockSource.User, c.BlockSource.Password), nil
+	case "blockdir":
+		if c.BlockSource.Dir == "" {
+			return nil, errors.New("BlockSource Dir is required")
+		}
+		return blocksource.NewBlockDir(c.BlockSource.Dir), nil
+	}
+	return nil, errors.New("unknown BlockSource Type: " + c.BlockSource.Type)
+}
 func (c Config) GetRuneLogo() (mime string, data []byte) {
 	if c.Etching != nil && c.Etching.Logo != "" {
 		mime, err := getContentType(c.Etching.Logo)
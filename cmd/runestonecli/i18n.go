@@ -21,6 +21,9 @@ func init() {
 	initString("Your address is: ", "您的地址是：")
 	initString("Etching rune encipher error:", "发行符文配置有误")
 	initString("Etching:%s, data:%x", "符文配置:%s, 编码后数据:%x")
+	initString("GetBlockHeight error:", "获取区块高度错误：")
+	initString("BlockSource error:", "区块数据源配置错误：")
+	initString("Rune name cannot be etched:", "符文名称无法发行：")
 	initString("BuildRuneEtchingTxs error:", "发行符文交易构建错误")
 	initString("commit Tx: %x\n", "提交交易: %x\n")
 	initString("reveal Tx: %x\n", "揭示交易: %x\n")
@@ -38,6 +41,24 @@ func init() {
 	initString("Mint Rune[%s] data: 0x%x\n", "挖掘符文[%s] 数据: 0x%x\n")
 	initString("BuildMintRuneTx error:", "构建挖掘符文交易错误：")
 	initString("mint rune tx: %x\n", "挖掘符文交易: %x\n")
+	initString("Finalize signed PSBT", "完成已签名的PSBT")
+	initString("WritePsbtToFile", "写入未签名PSBT到文件")
+	initString("write PSBT file error:", "写入PSBT文件错误：")
+	initString("write to file:", "已写入文件：")
+	initString("Signed commit PSBT file", "已签名的提交交易PSBT文件")
+	initString("Signed reveal PSBT file, empty if none", "已签名的揭示交易PSBT文件，没有则留空")
+	initString("read PSBT file error:", "读取PSBT文件错误：")
+	initString("finalize PSBT error:", "完成PSBT错误：")
+	initString("Show rune balances", "查看符文余额")
+	initString("Open index error:", "打开符文索引错误：")
+	initString("GetUtxos error:", "获取UTXO错误：")
+	initString("Rune balances error:", "符文余额错误：")
+	initString("Indexed up to block %d\n", "已索引到区块 %d\n")
+	initString("No blocks indexed yet", "尚未索引任何区块")
+	initString("BTC: %d sats in %d utxos, %d without runes\n", "BTC: %d 聪，共 %d 个UTXO，其中 %d 个不含符文\n")
+	initString("No runes", "没有符文")
+	initString("Rune balances:", "符文余额：")
+	initString("Rune outputs:", "含符文的输出：")
 }
 func initString(english, chinese string) {
 	key := english
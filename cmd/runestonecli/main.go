@@ -7,9 +7,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil/psbt"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/bxelab/runestone"
+	"github.com/bxelab/runestone/blocksource"
+	"github.com/bxelab/runestone/index"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/viper"
 	"golang.org/x/text/message"
@@ -24,7 +28,7 @@ func main() {
 	checkAndPrintConfig()
 
 	// 显示多语言文本
-	items := []string{i18n("Etching a new rune"), i18n("Mint rune")}
+	items := []string{i18n("Etching a new rune"), i18n("Mint rune"), i18n("Finalize signed PSBT"), i18n("Show rune balances")}
 	prompt := promptui.Select{
 		Label: i18n("Please select an option"),
 		Items: items,
@@ -43,6 +47,12 @@ func main() {
 		BuildMintTxs()
 
 	}
+	if optionIdx == 2 { //Finalize signed PSBT
+		FinalizePsbtTxs()
+	}
+	if optionIdx == 3 { //Show rune balances
+		ShowBalances()
+	}
 }
 
 func loadConfig() {
@@ -60,7 +70,7 @@ func loadConfig() {
 }
 func checkAndPrintConfig() {
 	//check privatekey and print address
-	_, addr, err := config.GetPrivateKeyAddr()
+	_, addr, err := config.GetPublicKeyAddr()
 	if err != nil {
 		p.Println("Private key error:", err.Error())
 		return
@@ -75,19 +85,48 @@ func BuildEtchingTxs() {
 		return
 	}
 	rs := runestone.Runestone{Etching: etching}
-	data, err := rs.Encipher()
+	data, err := config.GetChain().Encipher(&rs)
 	if err != nil {
 		p.Println("Etching rune encipher error:", err.Error())
 		return
 	}
 	etchJson, _ := json.Marshal(etching)
 	p.Printf("Etching:%s, data:%x", string(etchJson), data)
-	commitment := etching.Rune.Commitment()
 	btcConnector := NewMempoolConnector(config)
-	prvKey, address, _ := config.GetPrivateKeyAddr()
+	source, err := config.GetBlockSource()
+	if err != nil {
+		p.Println("BlockSource error:", err.Error())
+		return
+	}
+	height, err := source.TipHeight()
+	if err != nil {
+		p.Println("GetBlockHeight error:", err.Error())
+		return
+	}
+	// check the name in the earliest block the reveal tx can be in, once the
+	// commit tx mined in the next block has matured
+	needsCommitment, err := validateEtchingName(etching.Rune, height+config.GetChain().CommitConfirmations)
+	if err != nil {
+		p.Println("Rune name cannot be etched:", err.Error())
+		return
+	}
+	var commitment []byte
+	if needsCommitment {
+		commitment = etching.Rune.Commitment()
+	}
+	pubKey, address, err := config.GetPublicKeyAddr()
+	if err != nil {
+		p.Println("Private key error:", err.Error())
+		return
+	}
 	utxos, err := btcConnector.GetUtxos(address)
-	var cTx, rTx []byte
 	mime, logoData := config.GetRuneLogo()
+	prvKey, _, _ := config.GetPrivateKeyAddr()
+	if prvKey == nil { // no private key, leave the signing to a wallet
+		WriteEtchingPsbts(pubKey, utxos, data, commitment, address)
+		return
+	}
+	var cTx, rTx []byte
 	if len(mime) == 0 {
 		cTx, rTx, err = BuildRuneEtchingTxs(prvKey, utxos, data, commitment, config.GetFeePerByte(), config.GetUtxoAmount(), config.GetNetwork(), address)
 	} else {
@@ -99,7 +138,7 @@ func BuildEtchingTxs() {
 	}
 	p.Printf("commit Tx: %x\n", cTx)
 	p.Printf("reveal Tx: %x\n", rTx)
-	items := []string{i18n("SendTx"), i18n("WriteTxToFile")}
+	items := []string{i18n("SendTx"), i18n("WriteTxToFile"), i18n("WritePsbtToFile")}
 	prompt := promptui.Select{
 		Label: i18n("How to process the transaction?"),
 		Items: items,
@@ -112,17 +151,146 @@ func BuildEtchingTxs() {
 		return
 	}
 	if optionIdx == 0 { //Direct send
-		SendTx(btcConnector, cTx, rTx)
+		SendTx(cTx, rTx)
 	}
 	if optionIdx == 1 { //write to file
 		WriteFile(string(etchJson), cTx, rTx)
 	}
+	if optionIdx == 2 { //write unsigned psbt to file
+		WriteEtchingPsbts(pubKey, utxos, data, commitment, address)
+	}
+}
+
+// validateEtchingName checks the name with ValidateEtchingName, looking up
+// runes that are already etched in the index at IndexPath if there is one.
+func validateEtchingName(r *runestone.Rune, height uint64) (bool, error) {
+	if config.IndexPath == "" {
+		return runestone.ValidateEtchingName(r, height, config.GetChain(), nil)
+	}
+	idx, closeIndex, err := config.OpenIndex()
+	if err != nil {
+		return false, err
+	}
+	defer closeIndex()
+	return runestone.ValidateEtchingName(r, height, config.GetChain(), idx)
+}
+
+// WriteEtchingPsbts writes the unsigned commit and reveal tx of an etching to
+// commit.psbt and reveal.psbt, for signing in a separate wallet.
+func WriteEtchingPsbts(pubKey *btcec.PublicKey, utxos []*Utxo, data []byte, commitment []byte, address string) {
+	var cPsbt, rPsbt *psbt.Packet
+	var err error
+	mime, logoData := config.GetRuneLogo()
+	if len(mime) == 0 {
+		cPsbt, rPsbt, err = BuildRuneEtchingPsbts(pubKey, utxos, data, commitment, config.GetFeePerByte(), config.GetUtxoAmount(), config.GetNetwork(), address)
+	} else {
+		cPsbt, rPsbt, err = BuildInscriptionPsbts(pubKey, utxos, mime, logoData, config.GetFeePerByte(), config.GetUtxoAmount(), config.GetNetwork(), commitment, data)
+	}
+	if err != nil {
+		p.Println("BuildRuneEtchingTxs error:", err.Error())
+		return
+	}
+	WritePsbtFiles(cPsbt, rPsbt)
 }
 
-func SendTx(connector *MempoolConnector, ctx []byte, rtx []byte) {
+// WritePsbtFiles writes the commit and, if any, the reveal PSBT to
+// commit.psbt and reveal.psbt.
+func WritePsbtFiles(commitPsbt, revealPsbt *psbt.Packet) {
+	if err := WritePsbtFile("commit.psbt", commitPsbt); err != nil {
+		p.Println("write PSBT file error:", err.Error())
+		return
+	}
+	p.Println("write to file:", "commit.psbt")
+	if revealPsbt == nil {
+		return
+	}
+	if err := WritePsbtFile("reveal.psbt", revealPsbt); err != nil {
+		p.Println("write PSBT file error:", err.Error())
+		return
+	}
+	p.Println("write to file:", "reveal.psbt")
+}
+
+// FinalizePsbtTxs reads the signed commit and reveal PSBTs, finalizes them and
+// sends or writes the extracted txs like the other flows.
+func FinalizePsbtTxs() {
+	commitPrompt := promptui.Prompt{
+		Label:   i18n("Signed commit PSBT file"),
+		Default: "commit.psbt",
+	}
+	commitFile, err := commitPrompt.Run()
+	if err != nil {
+		p.Printf("Prompt failed %v", err)
+		return
+	}
+	revealPrompt := promptui.Prompt{
+		Label:   i18n("Signed reveal PSBT file, empty if none"),
+		Default: "reveal.psbt",
+	}
+	revealFile, err := revealPrompt.Run()
+	if err != nil {
+		p.Printf("Prompt failed %v", err)
+		return
+	}
+	commitPsbt, err := ReadPsbtFile(commitFile)
+	if err != nil {
+		p.Println("read PSBT file error:", err.Error())
+		return
+	}
+	var revealPsbt *psbt.Packet
+	if revealFile != "" {
+		revealPsbt, err = ReadPsbtFile(revealFile)
+		if err != nil {
+			p.Println("read PSBT file error:", err.Error())
+			return
+		}
+	}
+	cTx, rTx, err := FinalizeCommitRevealPsbts(commitPsbt, revealPsbt)
+	if err != nil {
+		p.Println("finalize PSBT error:", err.Error())
+		return
+	}
+	p.Printf("commit Tx: %x\n", cTx)
+	if rTx != nil {
+		p.Printf("reveal Tx: %x\n", rTx)
+	}
+	items := []string{i18n("SendTx"), i18n("WriteTxToFile")}
+	prompt := promptui.Select{
+		Label: i18n("How to process the transaction?"),
+		Items: items,
+	}
+
+	optionIdx, _, err := prompt.Run()
+
+	if err != nil {
+		p.Printf("Prompt failed %v", err)
+		return
+	}
+	if optionIdx == 0 { //Direct send
+		SendTx(cTx, rTx)
+	}
+	if optionIdx == 1 { //write to file
+		writeTxFile("Finalized PSBT: "+commitFile, cTx, rTx)
+	}
+}
+
+// SendTx broadcasts the commit tx to the configured block source and, once it
+// has enough confirmations, the reveal tx.
+func SendTx(ctx []byte, rtx []byte) {
+	source, err := config.GetBlockSource()
+	if err != nil {
+		p.Println("BlockSource error:", err.Error())
+		return
+	}
+	// the commit tx can only be mined above the current tip
+	start, err := source.TipHeight()
+	if err != nil {
+		p.Println("GetBlockHeight error:", err.Error())
+		return
+	}
 	tx := wire.NewMsgTx(wire.TxVersion)
 	tx.Deserialize(bytes.NewReader(ctx))
-	ctxHash, err := connector.SendRawTransaction(tx, false)
+	ctxHash, err := source.SendRawTransaction(tx)
 	if err != nil {
 		p.Println("SendRawTransaction error:", err.Error())
 		return
@@ -136,15 +304,16 @@ func SendTx(connector *MempoolConnector, ctx []byte, rtx []byte) {
 	//wail ctx tx confirm
 	lock.Lock()
 	go func(ctxHash *chainhash.Hash) {
+		confirmations := newConfirmationWatcher(source, ctxHash, start+1)
 		for {
 			time.Sleep(30 * time.Second)
-			txInfo, err := connector.GetTxByHash(ctxHash.String())
+			n, err := confirmations.get()
 			if err != nil {
 				p.Println("GetTransaction error:", err.Error())
 				continue
 			}
-			p.Println("commit tx confirmations:", txInfo.Confirmations)
-			if txInfo.Confirmations > runestone.COMMIT_CONFIRMATIONS {
+			p.Println("commit tx confirmations:", n)
+			if n > config.GetChain().CommitConfirmations {
 				break
 			}
 		}
@@ -153,7 +322,7 @@ func SendTx(connector *MempoolConnector, ctx []byte, rtx []byte) {
 	lock.Lock() //wait
 	tx = wire.NewMsgTx(wire.TxVersion)
 	tx.Deserialize(bytes.NewReader(rtx))
-	rtxHash, err := connector.SendRawTransaction(tx, false)
+	rtxHash, err := source.SendRawTransaction(tx)
 	if err != nil {
 		p.Println("SendRawTransaction error:", err.Error())
 		return
@@ -161,9 +330,58 @@ func SendTx(connector *MempoolConnector, ctx []byte, rtx []byte) {
 	p.Println("Etch complete, reveal tx hash:", rtxHash)
 }
 
+// confirmationWatcher counts the confirmations of a tx. It asks sources that
+// implement blocksource.Confirmer, and otherwise, as for a block directory,
+// looks for the tx in the blocks added since it was sent.
+type confirmationWatcher struct {
+	source blocksource.BlockSource
+	txid   *chainhash.Hash
+	next   uint64 // next block to look in
+	height uint64 // block holding the tx, 0 until found
+}
+
+func newConfirmationWatcher(source blocksource.BlockSource, txid *chainhash.Hash, from uint64) *confirmationWatcher {
+	return &confirmationWatcher{source: source, txid: txid, next: from}
+}
+
+func (w *confirmationWatcher) get() (uint64, error) {
+	if confirmer, ok := w.source.(blocksource.Confirmer); ok {
+		return confirmer.Confirmations(w.txid)
+	}
+	tip, err := w.source.TipHeight()
+	if err != nil {
+		return 0, err
+	}
+	for w.height == 0 && w.next <= tip {
+		hash, err := w.source.BlockHash(w.next)
+		if err != nil {
+			return 0, err
+		}
+		block, err := w.source.Block(hash)
+		if err != nil {
+			return 0, err
+		}
+		for _, tx := range block.Transactions {
+			if tx.TxHash() == *w.txid {
+				w.height = w.next
+			}
+		}
+		w.next++
+	}
+	if w.height == 0 || tip < w.height {
+		return 0, nil
+	}
+	return tip - w.height + 1, nil
+}
+
 var lock sync.Mutex
 
 func WriteFile(etching string, tx []byte, tx2 []byte) {
+	writeTxFile("Etching: "+etching, tx, tx2)
+}
+
+// writeTxFile appends the txs to tx.txt under label.
+func writeTxFile(label string, tx []byte, tx2 []byte) {
 	//write to file
 	file, err := os.OpenFile("tx.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -172,7 +390,7 @@ func WriteFile(etching string, tx []byte, tx2 []byte) {
 	}
 	defer file.Close()
 	file.WriteString(time.Now().String())
-	file.WriteString("Etching: " + etching)
+	file.WriteString(label)
 	file.WriteString("\n")
 	file.WriteString("Commit Tx: " + p.Sprintf("%x", tx))
 	file.WriteString("\n")
@@ -191,7 +409,7 @@ func BuildMintTxs() {
 
 	}
 	r := runestone.Runestone{Mint: runeId}
-	runeData, err := r.Encipher()
+	runeData, err := config.GetChain().Encipher(&r)
 	if err != nil {
 		p.Println(err)
 	}
@@ -199,15 +417,24 @@ func BuildMintTxs() {
 	//dataString, _ := txscript.DisasmString(data)
 	//p.Printf("Mint Script: %s\n", dataString)
 	btcConnector := NewMempoolConnector(config)
-	prvKey, address, _ := config.GetPrivateKeyAddr()
+	pubKey, address, err := config.GetPublicKeyAddr()
+	if err != nil {
+		p.Println("Private key error:", err.Error())
+		return
+	}
 	utxos, err := btcConnector.GetUtxos(address)
+	prvKey, _, _ := config.GetPrivateKeyAddr()
+	if prvKey == nil { // no private key, leave the signing to a wallet
+		WriteMintPsbt(pubKey, utxos, address, runeData)
+		return
+	}
 	tx, err := BuildTransferBTCTx(prvKey, utxos, address, config.GetUtxoAmount(), config.GetFeePerByte(), config.GetNetwork(), runeData)
 	if err != nil {
 		p.Println("BuildMintRuneTx error:", err.Error())
 		return
 	}
 	p.Printf("mint rune tx: %x\n", tx)
-	items := []string{i18n("SendTx"), i18n("WriteTxToFile")}
+	items := []string{i18n("SendTx"), i18n("WriteTxToFile"), i18n("WritePsbtToFile")}
 	prompt := promptui.Select{
 		Label: i18n("How to process the transaction?"),
 		Items: items,
@@ -220,9 +447,78 @@ func BuildMintTxs() {
 		return
 	}
 	if optionIdx == 0 { //Direct send
-		SendTx(btcConnector, tx, nil)
+		SendTx(tx, nil)
 	}
 	if optionIdx == 1 { //write to file
 		WriteFile(p.Sprintf("Mint rune[%s]", runeId.String()), tx, nil)
 	}
+	if optionIdx == 2 { //write unsigned psbt to file
+		WriteMintPsbt(pubKey, utxos, address, runeData)
+	}
+}
+
+// WriteMintPsbt writes the unsigned mint tx to commit.psbt.
+func WriteMintPsbt(pubKey *btcec.PublicKey, utxos []*Utxo, address string, runeData []byte) {
+	packet, err := BuildTransferBTCPsbt(pubKey, utxos, address, config.GetUtxoAmount(), config.GetFeePerByte(), config.GetNetwork(), runeData)
+	if err != nil {
+		p.Println("BuildMintRuneTx error:", err.Error())
+		return
+	}
+	WritePsbtFiles(packet, nil)
+}
+
+// ShowBalances lists the runes held by the UTXOs of the address, looking them
+// up in the local index.
+func ShowBalances() {
+	_, address, err := config.GetPublicKeyAddr()
+	if err != nil {
+		p.Println("Private key error:", err.Error())
+		return
+	}
+	idx, closeIndex, err := config.OpenIndex()
+	if err != nil {
+		p.Println("Open index error:", err.Error())
+		return
+	}
+	defer closeIndex()
+	btcConnector := NewMempoolConnector(config)
+	utxos, err := btcConnector.GetUtxos(address)
+	if err != nil {
+		p.Println("GetUtxos error:", err.Error())
+		return
+	}
+	list := make([]index.UTXO, len(utxos))
+	for i, utxo := range utxos {
+		list[i] = index.UTXO{Outpoint: utxo.OutPoint(), Value: utxo.Value}
+	}
+	view, err := index.NewAddressView(idx, list)
+	if err != nil {
+		p.Println("Rune balances error:", err.Error())
+		return
+	}
+	if next := idx.NextHeight(); next > idx.Chain().FirstRuneHeight {
+		p.Printf("Indexed up to block %d\n", next-1)
+	} else {
+		p.Println("No blocks indexed yet")
+	}
+	p.Printf("BTC: %d sats in %d utxos, %d without runes\n", view.Value, len(list), len(view.Cardinal))
+	if len(view.Totals) == 0 {
+		p.Println("No runes")
+		return
+	}
+	p.Println("Rune balances:")
+	for _, total := range view.Totals {
+		p.Printf("  %s (%s)\n", total, total.ID)
+	}
+	p.Println("Rune outputs:")
+	for _, utxo := range view.Runes {
+		p.Printf("  %s %d sats\n", utxo.Outpoint, utxo.Value)
+		for _, balance := range utxo.Balances {
+			entry, err := idx.RuneEntry(balance.ID)
+			if err != nil || entry == nil {
+				continue
+			}
+			p.Printf("    %s %s\n", entry.SpacedRune, entry.Pile(balance.Amount))
+		}
+	}
 }
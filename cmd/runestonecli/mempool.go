@@ -5,22 +5,22 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"strings"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
-	"github.com/pkg/errors"
+	"github.com/bxelab/runestone/blocksource"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 )
 
+// MempoolConnector adds the address queries of the mempool.space API to its
+// blocksource.Esplora.
 type MempoolConnector struct {
-	baseUrl string
+	*blocksource.Esplora
 	network *chaincfg.Params
 }
 
@@ -38,54 +38,14 @@ func NewMempoolConnector(config Config) *MempoolConnector {
 	//	log.Fatal("mempool don't support other netParams")
 	//}
 	connector := &MempoolConnector{
-		baseUrl: baseURL,
+		Esplora: blocksource.NewEsplora(baseURL),
 		network: config.GetNetwork(),
 	}
 	return connector
 }
 
-func (m MempoolConnector) GetBlockHeight() (uint64, error) {
-	res, err := m.request(http.MethodGet, "/blocks/tip/height", nil)
-	if err != nil {
-		return 0, err
-	}
-	var height uint64
-	err = json.Unmarshal(res, &height)
-	if err != nil {
-		return 0, err
-	}
-	log.Printf("found latest block height %d", height)
-	return height, nil
-
-}
-
-func (m MempoolConnector) GetBlockHashByHeight(height uint64) ([]byte, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/block-height/%d", height), nil)
-	if err != nil {
-		return nil, err
-	}
-	hashString := string(res)
-	hash, err := hex.DecodeString(hashString)
-	log.Printf("found block hash %x by height:%d", hash, height)
-	return hash, nil
-}
-
-func (m MempoolConnector) GetBlockByHash(blockHash Hash) (*wire.MsgBlock, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/block/%s/raw", blockHash), nil)
-	if err != nil {
-		return nil, err
-	}
-	//unmarshal the response
-	block := &wire.MsgBlock{}
-	if err := block.Deserialize(bytes.NewReader(res)); err != nil {
-		return nil, err
-	}
-	log.Printf("found block %s", blockHash)
-	return block, nil
-}
-
 func (m MempoolConnector) GetHeaderByHash(h Hash) (*wire.BlockHeader, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/block/%s/header", h), nil)
+	res, err := m.Request(http.MethodGet, fmt.Sprintf("/block/%s/header", h), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -102,16 +62,8 @@ func (m MempoolConnector) GetHeaderByHash(h Hash) (*wire.BlockHeader, error) {
 	return header, nil
 }
 
-func (m MempoolConnector) GetBlockByHeight(height uint64) (*wire.MsgBlock, error) {
-	hash, err := m.GetBlockHashByHeight(height)
-	if err != nil {
-		return nil, err
-	}
-	return m.GetBlockByHash(Hash(hash))
-}
-
 func (m MempoolConnector) GetBlockTxIDS(bh Hash) ([]Hash, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/block/%s/txids", bh), nil)
+	res, err := m.Request(http.MethodGet, fmt.Sprintf("/block/%s/txids", bh), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -128,30 +80,8 @@ func (m MempoolConnector) GetBlockTxIDS(bh Hash) ([]Hash, error) {
 	log.Printf("found %d txids for block %s", len(hashes), bh)
 	return hashes, nil
 }
-func (m MempoolConnector) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error) {
-	log.Printf("send tx %s to bitcoin network", tx.TxHash())
-	//th := tx.TxHash()
-	//return &th, nil
-
-	var buf bytes.Buffer
-	if err := tx.Serialize(&buf); err != nil {
-		return nil, err
-	}
-
-	res, err := m.request(http.MethodPost, "/tx", strings.NewReader(hex.EncodeToString(buf.Bytes())))
-	if err != nil {
-		return nil, err
-	}
-
-	txHash, err := chainhash.NewHashFromStr(string(res))
-	if err != nil {
-		return nil, errors.Wrap(err, fmt.Sprintf("failed to parse tx hash, %s", string(res)))
-	}
-	return txHash, nil
-}
-
 func (m MempoolConnector) GetUtxos(address string) ([]*Utxo, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/address/%s/utxo", address), nil)
+	res, err := m.Request(http.MethodGet, fmt.Sprintf("/address/%s/utxo", address), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -199,7 +129,7 @@ type txResponse struct {
 }
 
 func (m MempoolConnector) GetTxByHash(hash string) (*BtcTxInfo, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/tx/%s", hash), nil)
+	res, err := m.Request(http.MethodGet, fmt.Sprintf("/tx/%s", hash), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -214,32 +144,22 @@ func (m MempoolConnector) GetTxByHash(hash string) (*BtcTxInfo, error) {
 		txInfo.BlockHeight = resp.Status.BlockHeight
 		txInfo.BlockHash = HexToHash(resp.Status.BlockHash)
 		txInfo.BlockTime = uint64(resp.Status.BlockTime)
-		latest, err := m.GetBlockHeight()
+		latest, err := m.TipHeight()
 		if err != nil {
 			return nil, err
 		}
 		txInfo.Confirmations = latest - txInfo.BlockHeight + 1
 	}
-	tx, err := m.GetRawTxByHash(hash)
+	txid, err := chainhash.NewHashFromStr(hash)
 	if err != nil {
 		return nil, err
 	}
-	txInfo.Tx = tx
-	return txInfo, nil
-}
-
-func (m MempoolConnector) GetRawTxByHash(hash string) (*wire.MsgTx, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/tx/%s/raw", hash), nil)
+	tx, err := m.RawTransaction(txid)
 	if err != nil {
 		return nil, err
 	}
-	//unmarshal the response
-	tx := &wire.MsgTx{}
-	if err := tx.Deserialize(bytes.NewReader(res)); err != nil {
-		return nil, err
-	}
-	log.Printf("found tx %s", hash)
-	return tx, nil
+	txInfo.Tx = tx
+	return txInfo, nil
 }
 
 type mempoolUTXO struct {
@@ -254,30 +174,8 @@ type mempoolUTXO struct {
 	Value int64 `json:"value"`
 }
 
-func (m MempoolConnector) request(method, subPath string, requestBody io.Reader) ([]byte, error) {
-	url := fmt.Sprintf("%s%s", m.baseUrl, subPath)
-
-	req, err := http.NewRequest(method, url, requestBody)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create request")
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to send request")
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to read response body")
-	}
-
-	return body, nil
-}
-
 func (m MempoolConnector) GetBalance(address string) (uint64, error) {
-	res, err := m.request(http.MethodGet, fmt.Sprintf("/address/%s", address), nil)
+	res, err := m.Request(http.MethodGet, fmt.Sprintf("/address/%s", address), nil)
 	if err != nil {
 		return 0, err
 	}
@@ -26,80 +26,76 @@ const (
 
 func BuildInscriptionTxs(privateKey *btcec.PrivateKey, utxo []*Utxo, mime string, content []byte, feeRate int64, revealValue int64, net *chaincfg.Params, inscriptionAddData []byte, opReturnData []byte) ([]byte, []byte, error) {
 	//build 2 tx, 1 transfer BTC to taproot address, 2 inscription transfer taproot address to another address
-	pubKey := privateKey.PubKey()
-	receiver, err := getP2TRAddress(pubKey, net)
-	if err != nil {
-		return nil, nil, err
-	}
-	// 1. build inscription script
-	inscriptionScript, err := CreateInscriptionScript(pubKey, mime, content, inscriptionAddData)
-	if err != nil {
-		return nil, nil, err
-	}
-	inscriptionAddress, err := GetTapScriptAddress(pubKey, inscriptionScript, net)
-	if err != nil {
-		return nil, nil, err
-	}
-	inscriptionPkScript, _ := txscript.PayToAddrScript(inscriptionAddress)
-	// 2. build reveal tx
-	revealTx, totalPrevOutput, err := buildEmptyRevealTx(receiver, inscriptionScript, revealValue, feeRate, opReturnData)
-	if err != nil {
-		return nil, nil, err
-	}
-	// 3. build commit tx
-	out := &wire.TxOut{
-		Value:    totalPrevOutput,
-		PkScript: inscriptionPkScript,
-	}
-	commitTx, err := buildCommitTx(utxo, out, feeRate, nil, true)
+	// 1. build commit and reveal tx
+	commitTx, revealTx, inscriptionScript, err := buildInscriptionTxs(privateKey.PubKey(), utxo, mime, content, feeRate, revealValue, net, inscriptionAddData, opReturnData)
 	if err != nil {
 		return nil, nil, err
 	}
-	// 4. completeRevealTx
-	revealTx, err = completeRevealTx(privateKey, commitTx, revealTx, inscriptionScript)
+	return signCommitRevealTxs(privateKey, utxo, commitTx, revealTx, inscriptionScript)
+}
+func BuildRuneEtchingTxs(privateKey *btcec.PrivateKey, utxo []*Utxo, runeOpReturnData []byte, runeCommitment []byte,
+	feeRate int64, revealValue int64, net *chaincfg.Params, toAddr string) ([]byte, []byte, error) {
+	//build 2 tx, 1 transfer BTC to taproot address, 2 inscription transfer taproot address to another address
+	// 1. build commit and reveal tx
+	commitTx, revealTx, inscriptionScript, err := buildRuneEtchingTxs(privateKey.PubKey(), utxo, runeOpReturnData, runeCommitment, feeRate, revealValue, net, toAddr)
 	if err != nil {
 		return nil, nil, err
 	}
-	// 5. sign commit tx
-	commitTx, err = signCommitTx(privateKey, utxo, commitTx)
+	return signCommitRevealTxs(privateKey, utxo, commitTx, revealTx, inscriptionScript)
+}
+
+// buildInscriptionTxs builds the unsigned commit and reveal tx of an
+// inscription, the reveal output goes back to the taproot address of pubKey.
+func buildInscriptionTxs(pubKey *btcec.PublicKey, utxo []*Utxo, mime string, content []byte, feeRate int64, revealValue int64, net *chaincfg.Params, inscriptionAddData []byte, opReturnData []byte) (*wire.MsgTx, *wire.MsgTx, []byte, error) {
+	receiver, err := getP2TRAddress(pubKey, net)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	// 6. serialize
-	commitTxBytes, err := serializeTx(commitTx)
+	inscriptionScript, err := CreateInscriptionScript(pubKey, mime, content, inscriptionAddData)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	revealTxBytes, err := serializeTx(revealTx)
+	commitTx, revealTx, err := buildCommitRevealTxs(pubKey, utxo, receiver, inscriptionScript, feeRate, revealValue, net, opReturnData)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	return commitTxBytes, revealTxBytes, nil
+	return commitTx, revealTx, inscriptionScript, nil
 }
-func BuildRuneEtchingTxs(privateKey *btcec.PrivateKey, utxo []*Utxo, runeOpReturnData []byte, runeCommitment []byte,
-	feeRate int64, revealValue int64, net *chaincfg.Params, toAddr string) ([]byte, []byte, error) {
-	//build 2 tx, 1 transfer BTC to taproot address, 2 inscription transfer taproot address to another address
-	pubKey := privateKey.PubKey()
+
+// buildRuneEtchingTxs builds the unsigned commit and reveal tx of a rune
+// etching, the reveal tx pays revealValue to toAddr.
+func buildRuneEtchingTxs(pubKey *btcec.PublicKey, utxo []*Utxo, runeOpReturnData []byte, runeCommitment []byte,
+	feeRate int64, revealValue int64, net *chaincfg.Params, toAddr string) (*wire.MsgTx, *wire.MsgTx, []byte, error) {
 	receiver, err := btcutil.DecodeAddress(toAddr, net)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	// 1. build inscription script
 	inscriptionScript, err := CreateCommitmentScript(pubKey, runeCommitment)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	commitTx, revealTx, err := buildCommitRevealTxs(pubKey, utxo, receiver, inscriptionScript, feeRate, revealValue, net, runeOpReturnData)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	return commitTx, revealTx, inscriptionScript, nil
+}
+
+// buildCommitRevealTxs builds a commit tx paying to the tap script address of
+// inscriptionScript and the reveal tx spending its first output.
+func buildCommitRevealTxs(pubKey *btcec.PublicKey, utxo []*Utxo, receiver btcutil.Address, inscriptionScript []byte,
+	feeRate int64, revealValue int64, net *chaincfg.Params, opReturnData []byte) (*wire.MsgTx, *wire.MsgTx, error) {
 	inscriptionAddress, err := GetTapScriptAddress(pubKey, inscriptionScript, net)
 	if err != nil {
 		return nil, nil, err
 	}
 	inscriptionPkScript, _ := txscript.PayToAddrScript(inscriptionAddress)
-	// 2. build reveal tx
-	revealTx, totalPrevOutput, err := buildEmptyRevealTx(receiver, inscriptionScript, revealValue, feeRate, runeOpReturnData)
+	// 1. build reveal tx
+	revealTx, totalPrevOutput, err := buildEmptyRevealTx(receiver, inscriptionScript, revealValue, feeRate, opReturnData)
 	if err != nil {
 		return nil, nil, err
 	}
-	// 3. build commit tx
+	// 2. build commit tx
 	out := &wire.TxOut{
 		Value:    totalPrevOutput,
 		PkScript: inscriptionPkScript,
@@ -108,17 +104,23 @@ func BuildRuneEtchingTxs(privateKey *btcec.PrivateKey, utxo []*Utxo, runeOpRetur
 	if err != nil {
 		return nil, nil, err
 	}
-	// 4. completeRevealTx
-	revealTx, err = completeRevealTx(privateKey, commitTx, revealTx, inscriptionScript)
+	//set commit tx hash to reveal tx input
+	revealTx.TxIn[0].PreviousOutPoint.Hash = commitTx.TxHash()
+	return commitTx, revealTx, nil
+}
+
+func signCommitRevealTxs(privateKey *btcec.PrivateKey, utxo []*Utxo, commitTx *wire.MsgTx, revealTx *wire.MsgTx, inscriptionScript []byte) ([]byte, []byte, error) {
+	// 1. completeRevealTx
+	revealTx, err := completeRevealTx(privateKey, commitTx, revealTx, inscriptionScript)
 	if err != nil {
 		return nil, nil, err
 	}
-	// 5. sign commit tx
+	// 2. sign commit tx
 	commitTx, err = signCommitTx(privateKey, utxo, commitTx)
 	if err != nil {
 		return nil, nil, err
 	}
-	// 6. serialize
+	// 3. serialize
 	commitTxBytes, err := serializeTx(commitTx)
 	if err != nil {
 		return nil, nil, err
@@ -130,16 +132,8 @@ func BuildRuneEtchingTxs(privateKey *btcec.PrivateKey, utxo []*Utxo, runeOpRetur
 	return commitTxBytes, revealTxBytes, nil
 }
 func BuildTransferBTCTx(privateKey *btcec.PrivateKey, utxo []*Utxo, toAddr string, toAmount, feeRate int64, net *chaincfg.Params, runeData []byte) ([]byte, error) {
-	address, err := btcutil.DecodeAddress(toAddr, net)
-	if err != nil {
-		return nil, err
-	}
-	pkScript, err := txscript.PayToAddrScript(address)
-	if err != nil {
-		return nil, err
-	}
 	// 1. build tx
-	transferTx, err := buildCommitTx(utxo, wire.NewTxOut(toAmount, pkScript), feeRate, runeData, true)
+	transferTx, err := buildTransferBTCTx(utxo, toAddr, toAmount, feeRate, net, runeData)
 	if err != nil {
 		return nil, err
 	}
@@ -156,6 +150,18 @@ func BuildTransferBTCTx(privateKey *btcec.PrivateKey, utxo []*Utxo, toAddr strin
 	return commitTxBytes, nil
 }
 
+func buildTransferBTCTx(utxo []*Utxo, toAddr string, toAmount, feeRate int64, net *chaincfg.Params, runeData []byte) (*wire.MsgTx, error) {
+	address, err := btcutil.DecodeAddress(toAddr, net)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+	return buildCommitTx(utxo, wire.NewTxOut(toAmount, pkScript), feeRate, runeData, true)
+}
+
 func VerifyTx(rawTx string, prevTxOutScript []byte, prevTxOutValue int64) error {
 	txBytes, err := hex.DecodeString(rawTx)
 	if err != nil {
@@ -318,13 +324,19 @@ func completeRevealTx(privateKey *btcec.PrivateKey, commitTx *wire.MsgTx, reveal
 	revealTx.TxIn[0].Witness = wire.TxWitness{signature.Serialize(), inscriptionScript, controlBlockWitness}
 
 	// check tx max tx weight
+	if err := checkRevealWeight(revealTx); err != nil {
+		return nil, err
+	}
 
+	return revealTx, nil
+}
+
+func checkRevealWeight(revealTx *wire.MsgTx) error {
 	revealWeight := blockchain.GetTransactionWeight(btcutil.NewTx(revealTx))
 	if revealWeight > MaxStandardTxWeight {
-		return nil, errors.New(fmt.Sprintf("reveal(index %d) transaction weight greater than %d (MAX_STANDARD_TX_WEIGHT): %d", 0, MaxStandardTxWeight, revealWeight))
+		return errors.New(fmt.Sprintf("reveal(index %d) transaction weight greater than %d (MAX_STANDARD_TX_WEIGHT): %d", 0, MaxStandardTxWeight, revealWeight))
 	}
-
-	return revealTx, nil
+	return nil
 }
 
 func signCommitTx(prvKey *btcec.PrivateKey, utxos []*Utxo, commitTx *wire.MsgTx) (*wire.MsgTx, error) {
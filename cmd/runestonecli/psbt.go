@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BuildInscriptionPsbts builds the same commit and reveal tx as
+// BuildInscriptionTxs, but returns them as unsigned BIP174 PSBTs so that they
+// can be signed by a separate wallet holding the private key of pubKey.
+func BuildInscriptionPsbts(pubKey *btcec.PublicKey, utxo []*Utxo, mime string, content []byte, feeRate int64, revealValue int64, net *chaincfg.Params, inscriptionAddData []byte, opReturnData []byte) (*psbt.Packet, *psbt.Packet, error) {
+	commitTx, revealTx, inscriptionScript, err := buildInscriptionTxs(pubKey, utxo, mime, content, feeRate, revealValue, net, inscriptionAddData, opReturnData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newCommitRevealPsbts(pubKey, utxo, commitTx, revealTx, inscriptionScript)
+}
+
+// BuildRuneEtchingPsbts builds the same commit and reveal tx as
+// BuildRuneEtchingTxs as unsigned BIP174 PSBTs.
+func BuildRuneEtchingPsbts(pubKey *btcec.PublicKey, utxo []*Utxo, runeOpReturnData []byte, runeCommitment []byte,
+	feeRate int64, revealValue int64, net *chaincfg.Params, toAddr string) (*psbt.Packet, *psbt.Packet, error) {
+	commitTx, revealTx, inscriptionScript, err := buildRuneEtchingTxs(pubKey, utxo, runeOpReturnData, runeCommitment, feeRate, revealValue, net, toAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newCommitRevealPsbts(pubKey, utxo, commitTx, revealTx, inscriptionScript)
+}
+
+// BuildTransferBTCPsbt builds the same tx as BuildTransferBTCTx as an unsigned
+// BIP174 PSBT.
+func BuildTransferBTCPsbt(pubKey *btcec.PublicKey, utxo []*Utxo, toAddr string, toAmount, feeRate int64, net *chaincfg.Params, runeData []byte) (*psbt.Packet, error) {
+	transferTx, err := buildTransferBTCTx(utxo, toAddr, toAmount, feeRate, net, runeData)
+	if err != nil {
+		return nil, err
+	}
+	return newCommitPsbt(pubKey, utxo, transferTx)
+}
+
+func newCommitRevealPsbts(pubKey *btcec.PublicKey, utxo []*Utxo, commitTx *wire.MsgTx, revealTx *wire.MsgTx, inscriptionScript []byte) (*psbt.Packet, *psbt.Packet, error) {
+	commitPsbt, err := newCommitPsbt(pubKey, utxo, commitTx)
+	if err != nil {
+		return nil, nil, err
+	}
+	revealPsbt, err := newRevealPsbt(pubKey, commitTx, revealTx, inscriptionScript)
+	if err != nil {
+		return nil, nil, err
+	}
+	return commitPsbt, revealPsbt, nil
+}
+
+// newCommitPsbt wraps a tx spending the key path of pubKey's taproot utxos.
+func newCommitPsbt(pubKey *btcec.PublicKey, utxos []*Utxo, commitTx *wire.MsgTx) (*psbt.Packet, error) {
+	packet, err := psbt.NewFromUnsignedTx(commitTx)
+	if err != nil {
+		return nil, err
+	}
+	utxoList := UtxoList(utxos)
+	internalKey := schnorr.SerializePubKey(pubKey)
+	for i, txIn := range commitTx.TxIn {
+		txOut := utxoList.FetchPrevOutput(txIn.PreviousOutPoint)
+		if txOut == nil {
+			return nil, fmt.Errorf("utxo of input %d not found: %s", i, txIn.PreviousOutPoint)
+		}
+		packet.Inputs[i].WitnessUtxo = txOut
+		packet.Inputs[i].TaprootInternalKey = internalKey
+	}
+	return packet, nil
+}
+
+// newRevealPsbt wraps a reveal tx spending the script path of the first
+// output of commitTx, with the leaf script, control block and internal key
+// the signer needs filled in.
+func newRevealPsbt(pubKey *btcec.PublicKey, commitTx *wire.MsgTx, revealTx *wire.MsgTx, inscriptionScript []byte) (*psbt.Packet, error) {
+	leafNode := txscript.NewBaseTapLeaf(inscriptionScript)
+	proof := &txscript.TapscriptProof{
+		TapLeaf:  leafNode,
+		RootNode: leafNode,
+	}
+	controlBlock := proof.ToControlBlock(pubKey)
+	controlBlockWitness, err := controlBlock.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	// check tx max tx weight with a mock signature
+	mockTx := revealTx.Copy()
+	mockTx.TxIn[0].Witness = wire.TxWitness{make([]byte, 64), inscriptionScript, controlBlockWitness}
+	if err := checkRevealWeight(mockTx); err != nil {
+		return nil, err
+	}
+	packet, err := psbt.NewFromUnsignedTx(revealTx)
+	if err != nil {
+		return nil, err
+	}
+	rootHash := leafNode.TapHash()
+	input := &packet.Inputs[0]
+	input.WitnessUtxo = commitTx.TxOut[0]
+	input.TaprootInternalKey = schnorr.SerializePubKey(pubKey)
+	input.TaprootMerkleRoot = rootHash[:]
+	input.TaprootLeafScript = []*psbt.TaprootTapLeafScript{{
+		ControlBlock: controlBlockWitness,
+		Script:       inscriptionScript,
+		LeafVersion:  txscript.BaseLeafVersion,
+	}}
+	return packet, nil
+}
+
+// FinalizePsbt finalizes every input of a signed PSBT and extracts the network
+// serialized tx.
+func FinalizePsbt(packet *psbt.Packet) ([]byte, error) {
+	if err := psbt.MaybeFinalizeAll(packet); err != nil {
+		return nil, err
+	}
+	tx, err := psbt.Extract(packet)
+	if err != nil {
+		return nil, err
+	}
+	return serializeTx(tx)
+}
+
+// FinalizeCommitRevealPsbts finalizes a signed commit PSBT and, if given, the
+// reveal PSBT spending it.
+func FinalizeCommitRevealPsbts(commitPsbt, revealPsbt *psbt.Packet) ([]byte, []byte, error) {
+	if revealPsbt != nil {
+		if len(revealPsbt.UnsignedTx.TxIn) == 0 {
+			return nil, nil, errors.New("reveal PSBT has no inputs")
+		}
+		commitHash := commitPsbt.UnsignedTx.TxHash()
+		if revealPsbt.UnsignedTx.TxIn[0].PreviousOutPoint.Hash != commitHash {
+			return nil, nil, errors.New("reveal PSBT does not spend the commit PSBT")
+		}
+	}
+	commitTx, err := FinalizePsbt(commitPsbt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revealPsbt == nil {
+		return commitTx, nil, nil
+	}
+	revealTx, err := FinalizePsbt(revealPsbt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return commitTx, revealTx, nil
+}
+
+// ReadPsbtFile reads a PSBT in base64 or binary form.
+func ReadPsbtFile(name string) (*psbt.Packet, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte("psbt\xff")) {
+		return psbt.NewFromRawBytes(bytes.NewReader(data), false)
+	}
+	return psbt.NewFromRawBytes(bytes.NewReader(bytes.TrimSpace(data)), true)
+}
+
+// WritePsbtFile writes a PSBT in base64, the form most wallets import.
+func WritePsbtFile(name string, packet *psbt.Packet) error {
+	b64, err := packet.B64Encode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(b64+"\n"), 0644)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWallet is a taproot key with a utxo paid to it.
+func testWallet(t *testing.T) (*btcec.PrivateKey, string, []*Utxo) {
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	address, err := GetP2TRAddress(privKey.PubKey(), &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	addr, err := getP2TRAddress(privKey.PubKey(), &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	utxo := &Utxo{TxHash: Hash{1, 2, 3}, Index: 1, Value: 100000, PkScript: pkScript}
+	return privKey, address, []*Utxo{utxo}
+}
+
+func prevOutFetcher(packet *psbt.Packet) *txscript.MultiPrevOutFetcher {
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for i, txIn := range packet.UnsignedTx.TxIn {
+		fetcher.AddPrevOut(txIn.PreviousOutPoint, packet.Inputs[i].WitnessUtxo)
+	}
+	return fetcher
+}
+
+// signKeyPath signs every input of packet like a wallet holding privKey,
+// using only the fields of the PSBT.
+func signKeyPath(t *testing.T, packet *psbt.Packet, privKey *btcec.PrivateKey) {
+	fetcher := prevOutFetcher(packet)
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx, fetcher)
+	for i := range packet.Inputs {
+		input := &packet.Inputs[i]
+		assert.Equal(t, schnorr.SerializePubKey(privKey.PubKey()), input.TaprootInternalKey)
+		sig, err := txscript.RawTxInTaprootSignature(packet.UnsignedTx, sigHashes, i,
+			input.WitnessUtxo.Value, input.WitnessUtxo.PkScript, input.TaprootMerkleRoot, txscript.SigHashDefault, privKey)
+		require.NoError(t, err)
+		input.TaprootKeySpendSig = sig
+	}
+}
+
+// signScriptPath signs the leaf script of the reveal input.
+func signScriptPath(t *testing.T, packet *psbt.Packet, privKey *btcec.PrivateKey) {
+	fetcher := prevOutFetcher(packet)
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx, fetcher)
+	input := &packet.Inputs[0]
+	require.Len(t, input.TaprootLeafScript, 1)
+	leaf := txscript.NewTapLeaf(input.TaprootLeafScript[0].LeafVersion, input.TaprootLeafScript[0].Script)
+	sig, err := txscript.RawTxInTapscriptSignature(packet.UnsignedTx, sigHashes, 0,
+		input.WitnessUtxo.Value, input.WitnessUtxo.PkScript, leaf, txscript.SigHashDefault, privKey)
+	require.NoError(t, err)
+	leafHash := leaf.TapHash()
+	input.TaprootScriptSpendSig = []*psbt.TaprootScriptSpendSig{{
+		XOnlyPubKey: input.TaprootInternalKey,
+		LeafHash:    leafHash[:],
+		Signature:   sig,
+		SigHash:     txscript.SigHashDefault,
+	}}
+}
+
+// verifyTx runs every input of the serialized tx through the script engine.
+func verifyTx(t *testing.T, raw []byte, fetcher txscript.PrevOutputFetcher) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	require.NoError(t, tx.Deserialize(bytes.NewReader(raw)))
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	for i, txIn := range tx.TxIn {
+		prevOut := fetcher.FetchPrevOutput(txIn.PreviousOutPoint)
+		engine, err := txscript.NewEngine(prevOut.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, fetcher)
+		require.NoError(t, err)
+		require.NoError(t, engine.Execute(), "input %d", i)
+	}
+	return tx
+}
+
+func TestEtchingPsbtsRoundTrip(t *testing.T) {
+	privKey, address, utxos := testWallet(t)
+	r, err := runestone.RuneFromString("AAAAAAAAAAAAAAA")
+	require.NoError(t, err)
+	data, err := runestone.Regtest.Encipher(&runestone.Runestone{Etching: &runestone.Etching{Rune: r}})
+	require.NoError(t, err)
+	commitPsbt, revealPsbt, err := BuildRuneEtchingPsbts(privKey.PubKey(), utxos, data, r.Commitment(),
+		5, 1000, &chaincfg.RegressionNetParams, address)
+	require.NoError(t, err)
+
+	// the PSBTs survive the files a wallet is handed
+	dir := t.TempDir()
+	require.NoError(t, WritePsbtFile(filepath.Join(dir, "commit.psbt"), commitPsbt))
+	require.NoError(t, WritePsbtFile(filepath.Join(dir, "reveal.psbt"), revealPsbt))
+	commitPsbt, err = ReadPsbtFile(filepath.Join(dir, "commit.psbt"))
+	require.NoError(t, err)
+	revealPsbt, err = ReadPsbtFile(filepath.Join(dir, "reveal.psbt"))
+	require.NoError(t, err)
+
+	signKeyPath(t, commitPsbt, privKey)
+	signScriptPath(t, revealPsbt, privKey)
+	commitFetcher := prevOutFetcher(commitPsbt)
+	revealFetcher := prevOutFetcher(revealPsbt)
+	commitRaw, revealRaw, err := FinalizeCommitRevealPsbts(commitPsbt, revealPsbt)
+	require.NoError(t, err)
+
+	commitTx := verifyTx(t, commitRaw, commitFetcher)
+	revealTx := verifyTx(t, revealRaw, revealFetcher)
+	assert.Equal(t, commitTx.TxHash(), revealTx.TxIn[0].PreviousOutPoint.Hash)
+	artifact, err := runestone.Regtest.Decipher(revealTx)
+	require.NoError(t, err)
+	require.NotNil(t, artifact.Runestone)
+	assert.Equal(t, r, artifact.Runestone.Etching.Rune)
+}
+
+func TestInscriptionPsbtsRoundTrip(t *testing.T) {
+	privKey, _, utxos := testWallet(t)
+	commitPsbt, revealPsbt, err := BuildInscriptionPsbts(privKey.PubKey(), utxos, "text/plain", []byte("hello"),
+		5, 1000, &chaincfg.RegressionNetParams, nil, nil)
+	require.NoError(t, err)
+
+	signKeyPath(t, commitPsbt, privKey)
+	signScriptPath(t, revealPsbt, privKey)
+	commitFetcher := prevOutFetcher(commitPsbt)
+	revealFetcher := prevOutFetcher(revealPsbt)
+	commitRaw, revealRaw, err := FinalizeCommitRevealPsbts(commitPsbt, revealPsbt)
+	require.NoError(t, err)
+	verifyTx(t, commitRaw, commitFetcher)
+	verifyTx(t, revealRaw, revealFetcher)
+}
+
+func TestTransferPsbtRoundTrip(t *testing.T) {
+	privKey, address, utxos := testWallet(t)
+	packet, err := BuildTransferBTCPsbt(privKey.PubKey(), utxos, address, 1000, 5, &chaincfg.RegressionNetParams, nil)
+	require.NoError(t, err)
+	signKeyPath(t, packet, privKey)
+	fetcher := prevOutFetcher(packet)
+	raw, err := FinalizePsbt(packet)
+	require.NoError(t, err)
+	verifyTx(t, raw, fetcher)
+}
+
+func TestFinalizeRejectsMismatchedReveal(t *testing.T) {
+	privKey, address, utxos := testWallet(t)
+	r, err := runestone.RuneFromString("AAAAAAAAAAAAAAA")
+	require.NoError(t, err)
+	commitPsbt, revealPsbt, err := BuildRuneEtchingPsbts(privKey.PubKey(), utxos, nil, r.Commitment(),
+		5, 1000, &chaincfg.RegressionNetParams, address)
+	require.NoError(t, err)
+
+	other, _, err := BuildInscriptionPsbts(privKey.PubKey(), utxos, "text/plain", []byte("other"),
+		5, 1000, &chaincfg.RegressionNetParams, nil, nil)
+	require.NoError(t, err)
+	_, _, err = FinalizeCommitRevealPsbts(other, revealPsbt)
+	assert.ErrorContains(t, err, "does not spend")
+
+	revealPsbt.UnsignedTx.TxIn = nil
+	_, _, err = FinalizeCommitRevealPsbts(commitPsbt, revealPsbt)
+	assert.ErrorContains(t, err, "no inputs")
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	ErrNoEtchedRune         = errors.New("transaction does not etch a named rune")
+	ErrNoCommitment         = errors.New("no input reveals the rune commitment")
+	ErrCommitmentNotTaproot = errors.New("rune commitment spends a non-taproot output")
+	ErrCommitmentImmature   = errors.New("rune commitment is not mature")
+	ErrUnknownCommitPrevout = errors.New("unknown output spent by rune commitment")
+)
+
+// ConfirmationsLookup returns the height of the block that confirmed the
+// transaction creating outpoint.
+type ConfirmationsLookup func(outpoint wire.OutPoint) (uint64, error)
+
+// ValidateCommitment checks that tx, which is to be included in the block at
+// currentHeight, reveals a valid commitment to the rune it etches. Both
+// runestones and cenotaphs that name a rune need one; unnamed etchings get a
+// reserved rune and do not, so they return ErrNoEtchedRune. It uses the
+// parameters of Mainnet, see Chain.ValidateCommitment for other chains.
+func ValidateCommitment(tx *wire.MsgTx, prevouts txscript.PrevOutputFetcher, currentHeight uint64, confirmations ConfirmationsLookup) error {
+	return Mainnet.ValidateCommitment(tx, prevouts, currentHeight, confirmations)
+}
+
+// ValidateCommitment is the ValidateCommitment function on c.
+func (c *Chain) ValidateCommitment(tx *wire.MsgTx, prevouts txscript.PrevOutputFetcher, currentHeight uint64, confirmations ConfirmationsLookup) error {
+	artifact, _ := c.Decipher(tx)
+	var r *Rune
+	if artifact == nil {
+		return ErrNoEtchedRune
+	}
+	if artifact.Runestone != nil && artifact.Runestone.Etching != nil {
+		r = artifact.Runestone.Etching.Rune
+	}
+	if artifact.Cenotaph != nil {
+		r = artifact.Cenotaph.Etching
+	}
+	if r == nil {
+		return ErrNoEtchedRune
+	}
+	return c.ValidateRuneCommitment(tx, *r, prevouts, currentHeight, confirmations)
+}
+
+// ValidateRuneCommitment checks that an input of tx reveals the commitment of r
+// in a tapscript, spends a taproot output, and that output has at least
+// COMMIT_CONFIRMATIONS confirmations in the block at currentHeight. It follows
+// tx_commits_to_rune in ord.
+func ValidateRuneCommitment(tx *wire.MsgTx, r Rune, prevouts txscript.PrevOutputFetcher, currentHeight uint64, confirmations ConfirmationsLookup) error {
+	return Mainnet.ValidateRuneCommitment(tx, r, prevouts, currentHeight, confirmations)
+}
+
+// ValidateRuneCommitment is the ValidateRuneCommitment function on c, which
+// requires c.CommitConfirmations confirmations.
+func (c *Chain) ValidateRuneCommitment(tx *wire.MsgTx, r Rune, prevouts txscript.PrevOutputFetcher, currentHeight uint64, confirmations ConfirmationsLookup) error {
+	commitment := r.Commitment()
+	result := ErrNoCommitment
+	for _, in := range tx.TxIn {
+		tapscript := UnversionedLeafScript(in.Witness)
+		if tapscript == nil {
+			continue
+		}
+		tokenizer := txscript.MakeScriptTokenizer(0, tapscript)
+		for tokenizer.Next() {
+			if tokenizer.Opcode() > txscript.OP_PUSHDATA4 || !bytes.Equal(tokenizer.Data(), commitment) {
+				continue
+			}
+			out := prevouts.FetchPrevOutput(in.PreviousOutPoint)
+			if out == nil {
+				return fmt.Errorf("%w: %s", ErrUnknownCommitPrevout, in.PreviousOutPoint)
+			}
+			if !txscript.IsPayToTaproot(out.PkScript) {
+				result = ErrCommitmentNotTaproot
+				continue
+			}
+			height, err := confirmations(in.PreviousOutPoint)
+			if err != nil {
+				return err
+			}
+			if currentHeight+1 >= height+c.CommitConfirmations {
+				return nil
+			}
+			result = fmt.Errorf("%w: confirmed at block %d", ErrCommitmentImmature, height)
+		}
+	}
+	return result
+}
+
+// UnversionedLeafScript returns the tapscript revealed by a script path spend
+// with witness, skipping the annex if there is one, or nil if the witness is
+// too short to be one.
+func UnversionedLeafScript(witness wire.TxWitness) []byte {
+	n := len(witness)
+	if n == 0 {
+		return nil
+	}
+	pos := 2
+	if last := witness[n-1]; n >= 2 && len(last) > 0 && last[0] == txscript.TaprootAnnexTag {
+		pos = 3
+	}
+	if n < pos {
+		return nil
+	}
+	return witness[n-pos]
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func commitmentTx(t *testing.T, r *Rune, witness wire.TxWitness) *wire.MsgTx {
+	script, err := (&Runestone{Etching: &Etching{Rune: r}}).Encipher()
+	assert.NoError(t, err)
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, witness))
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	return tx
+}
+
+func commitmentWitness(r Rune) wire.TxWitness {
+	tapscript, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_FALSE).AddOp(txscript.OP_IF).
+		AddData(r.Commitment()).AddOp(txscript.OP_ENDIF).Script()
+	return wire.TxWitness{make([]byte, 64), tapscript, make([]byte, 33)}
+}
+
+func TestValidateCommitment(t *testing.T) {
+	r := Rune{Value: STEPS[13].Add64(1)}
+	taproot := txscript.NewCannedPrevOutputFetcher(append([]byte{txscript.OP_1, txscript.OP_DATA_32}, make([]byte, 32)...), 10000)
+	confirmedAt := func(height uint64) ConfirmationsLookup {
+		return func(wire.OutPoint) (uint64, error) { return height, nil }
+	}
+
+	tx := commitmentTx(t, &r, commitmentWitness(r))
+	assert.NoError(t, ValidateCommitment(tx, taproot, 105, confirmedAt(100)))
+	assert.ErrorIs(t, ValidateCommitment(tx, taproot, 104, confirmedAt(100)), ErrCommitmentImmature)
+
+	p2wpkh := txscript.NewCannedPrevOutputFetcher(append([]byte{txscript.OP_0, txscript.OP_DATA_20}, make([]byte, 20)...), 10000)
+	assert.ErrorIs(t, ValidateCommitment(tx, p2wpkh, 105, confirmedAt(100)), ErrCommitmentNotTaproot)
+
+	lookupErr := errors.New("lookup failed")
+	assert.ErrorIs(t, ValidateCommitment(tx, taproot, 105, func(wire.OutPoint) (uint64, error) { return 0, lookupErr }), lookupErr)
+	assert.ErrorIs(t, ValidateCommitment(tx, txscript.NewMultiPrevOutFetcher(nil), 105, confirmedAt(100)), ErrUnknownCommitPrevout)
+
+	// the tapscript comes before the annex
+	annexed := commitmentTx(t, &r, append(commitmentWitness(r), []byte{txscript.TaprootAnnexTag}))
+	assert.NoError(t, ValidateCommitment(annexed, taproot, 105, confirmedAt(100)))
+
+	other := Rune{Value: r.Value.Add64(1)}
+	assert.ErrorIs(t, ValidateCommitment(commitmentTx(t, &r, commitmentWitness(other)), taproot, 105, confirmedAt(100)), ErrNoCommitment)
+	assert.ErrorIs(t, ValidateCommitment(commitmentTx(t, &r, nil), taproot, 105, confirmedAt(100)), ErrNoCommitment)
+	assert.ErrorIs(t, ValidateCommitment(commitmentTx(t, nil, commitmentWitness(r)), taproot, 105, confirmedAt(100)), ErrNoEtchedRune)
+	assert.ErrorIs(t, ValidateCommitment(wire.NewMsgTx(2), taproot, 105, confirmedAt(100)), ErrNoEtchedRune)
+
+	// cenotaphs that name a rune still reveal a commitment
+	cenotaph := commitmentTx(t, &r, commitmentWitness(r))
+	script, err := (&Runestone{Etching: &Etching{Rune: &r}, Edicts: []Edict{{Output: 5}}}).Encipher()
+	assert.NoError(t, err)
+	cenotaph.TxOut[0].PkScript = script
+	artifact, _ := (&Runestone{}).Decipher(cenotaph)
+	if assert.NotNil(t, artifact) && assert.NotNil(t, artifact.Cenotaph) {
+		assert.NoError(t, ValidateCommitment(cenotaph, taproot, 105, confirmedAt(100)))
+	}
+}
@@ -24,6 +24,9 @@ import (
 var ErrNone = errors.New("none")
 
 func Encode(n *big.Int) []byte {
+	if n.Sign() >= 0 && n.BitLen() <= 128 {
+		return AppendUvarint128(nil, uint128.FromBig(n))
+	}
 	var result []byte
 	for n.Cmp(big.NewInt(128)) >= 0 {
 		temp := new(big.Int).Set(n)
@@ -77,7 +80,7 @@ func EncodeUint8(n uint8) []byte {
 	return result
 }
 func EncodeUint128(n uint128.Uint128) []byte {
-	return Encode(n.Big())
+	return AppendUvarint128(nil, n)
 }
 func EncodeChar(r rune) []byte {
 	return EncodeUint32(uint32(r))
@@ -0,0 +1,222 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"lukechampine.com/uint128"
+)
+
+var (
+	ErrInvalidDecimal  = errors.New("invalid decimal")
+	ErrExcessPrecision = errors.New("excessive precision")
+	ErrAmountOverflow  = errors.New("amount out of range")
+	ErrAmountUnderflow = errors.New("amount underflow")
+	// ErrDivisibilityMismatch is returned by arithmetic on piles of runes
+	// with different divisibilities.
+	ErrDivisibilityMismatch = errors.New("divisibility mismatch")
+)
+
+// Decimal is a decimal number such as 1234.5678, stored as Value scaled
+// down by Scale digits, like ord's Decimal. It is how amounts of runes are
+// written by users, before being converted to base units with Integer.
+type Decimal struct {
+	Value uint128.Uint128
+	Scale uint8
+}
+
+// ParseDecimal parses a non-negative decimal number. Either the integer or
+// the fractional part may be omitted, but not both, and trailing zeros of
+// the fractional part do not count towards its scale.
+func ParseDecimal(s string) (Decimal, error) {
+	integer, fraction, found := strings.Cut(s, ".")
+	if !found {
+		value, err := parseDigits(integer)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return Decimal{Value: value}, nil
+	}
+	if integer == "" && fraction == "" {
+		return Decimal{}, fmt.Errorf("%w: empty decimal", ErrInvalidDecimal)
+	}
+
+	value := uint128.Zero
+	if integer != "" {
+		var err error
+		if value, err = parseDigits(integer); err != nil {
+			return Decimal{}, err
+		}
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > MaxDivisibility {
+		return Decimal{}, fmt.Errorf("%w: %d decimal places", ErrExcessPrecision, len(fraction))
+	}
+	value, err := appendDigits(value, fraction)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return Decimal{Value: value, Scale: uint8(len(fraction))}, nil
+}
+
+// Integer returns d in the base units of a rune with the given divisibility,
+// failing with ErrExcessPrecision if d has more decimal places than the rune
+// and with ErrAmountOverflow if the amount does not fit in a uint128.
+func (d Decimal) Integer(divisibility uint8) (uint128.Uint128, error) {
+	if d.Scale > divisibility {
+		return uint128.Zero, fmt.Errorf("%w: %s has more than %d decimal places", ErrExcessPrecision, d, divisibility)
+	}
+	value := d.Value
+	for i := d.Scale; i < divisibility; i++ {
+		var err error
+		if value, err = checkedMul64(value, 10); err != nil {
+			return uint128.Zero, err
+		}
+	}
+	return value, nil
+}
+
+// String formats d without trailing zeros, as ord does.
+func (d Decimal) String() string {
+	digits := d.Value.String()
+	scale := int(d.Scale)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	integer, fraction := digits[:len(digits)-scale], strings.TrimRight(digits[len(digits)-scale:], "0")
+	if fraction == "" {
+		return integer
+	}
+	return integer + "." + fraction
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	decimal, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = decimal
+	return nil
+}
+
+// Pile is an amount of a rune in base units, with the divisibility and
+// symbol needed to display it.
+type Pile struct {
+	Amount       uint128.Uint128
+	Divisibility uint8
+	Symbol       *rune
+}
+
+// Pile returns amount of the rune of e as a Pile.
+func (e *RuneEntry) Pile(amount uint128.Uint128) Pile {
+	return Pile{Amount: amount, Divisibility: e.Divisibility, Symbol: e.Symbol}
+}
+
+// Decimal returns the amount of p in whole runes.
+func (p Pile) Decimal() Decimal {
+	return Decimal{Value: p.Amount, Scale: p.Divisibility}
+}
+
+// String formats p like ord: the amount in whole runes followed by a
+// non-breaking space and the symbol of the rune, or ¤ if it has none.
+func (p Pile) String() string {
+	symbol := '¤'
+	if p.Symbol != nil {
+		symbol = *p.Symbol
+	}
+	return p.Decimal().String() + "\u00a0" + string(symbol)
+}
+
+// Add returns the sum of p and q, which must have the same divisibility.
+func (p Pile) Add(q Pile) (Pile, error) {
+	if p.Divisibility != q.Divisibility {
+		return Pile{}, ErrDivisibilityMismatch
+	}
+	amount, err := checkedAdd(p.Amount, q.Amount)
+	if err != nil {
+		return Pile{}, err
+	}
+	p.Amount = amount
+	return p, nil
+}
+
+// Sub returns p minus q, which must have the same divisibility.
+func (p Pile) Sub(q Pile) (Pile, error) {
+	if p.Divisibility != q.Divisibility {
+		return Pile{}, ErrDivisibilityMismatch
+	}
+	if p.Amount.Cmp(q.Amount) < 0 {
+		return Pile{}, ErrAmountUnderflow
+	}
+	p.Amount = p.Amount.Sub(q.Amount)
+	return p, nil
+}
+
+// Mul64 returns p multiplied by n, such as the amount of n mints.
+func (p Pile) Mul64(n uint64) (Pile, error) {
+	amount, err := checkedMul64(p.Amount, n)
+	if err != nil {
+		return Pile{}, err
+	}
+	p.Amount = amount
+	return p, nil
+}
+
+// parseDigits parses a non-empty string of decimal digits.
+func parseDigits(s string) (uint128.Uint128, error) {
+	if s == "" {
+		return uint128.Zero, fmt.Errorf("%w: no digits", ErrInvalidDecimal)
+	}
+	return appendDigits(uint128.Zero, s)
+}
+
+// appendDigits returns value followed by the decimal digits of s.
+func appendDigits(value uint128.Uint128, s string) (uint128.Uint128, error) {
+	for _, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return uint128.Zero, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+		}
+		var err error
+		if value, err = checkedMul64(value, 10); err != nil {
+			return uint128.Zero, err
+		}
+		if value, err = checkedAdd(value, uint128.From64(uint64(c-'0'))); err != nil {
+			return uint128.Zero, err
+		}
+	}
+	return value, nil
+}
+
+func checkedAdd(a, b uint128.Uint128) (uint128.Uint128, error) {
+	sum := a.AddWrap(b)
+	if sum.Cmp(a) < 0 {
+		return uint128.Zero, ErrAmountOverflow
+	}
+	return sum, nil
+}
+
+func checkedMul64(a uint128.Uint128, n uint64) (uint128.Uint128, error) {
+	if n != 0 && a.Cmp(uint128.Max.Div64(n)) > 0 {
+		return uint128.Zero, ErrAmountOverflow
+	}
+	return a.Mul64(n), nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+func TestParseDecimal(t *testing.T) {
+	caseFunc := func(s string, value uint64, scale uint8) {
+		d, err := ParseDecimal(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, Decimal{Value: uint128.From64(value), Scale: scale}, d, s)
+	}
+	caseFunc("0", 0, 0)
+	caseFunc("1234", 1234, 0)
+	caseFunc("1234.5678", 12345678, 4)
+	caseFunc("1.", 1, 0)
+	caseFunc(".5", 5, 1)
+	caseFunc("1.50", 15, 1)
+	caseFunc("1.000", 1, 0)
+	caseFunc("0.0001", 1, 4)
+
+	d, err := ParseDecimal("340282366920938463463374607431768211455")
+	require.NoError(t, err)
+	assert.Equal(t, uint128.Max, d.Value)
+
+	for _, s := range []string{"", ".", "-1", "1.2.3", "1e5", " 1", "1,5"} {
+		_, err := ParseDecimal(s)
+		assert.ErrorIs(t, err, ErrInvalidDecimal, s)
+	}
+	_, err = ParseDecimal("340282366920938463463374607431768211456")
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = ParseDecimal("34028236692093846346337460743176821145.6")
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = ParseDecimal("0.000000000000000000000000000000000000001")
+	assert.ErrorIs(t, err, ErrExcessPrecision)
+}
+
+func TestDecimalInteger(t *testing.T) {
+	caseFunc := func(s string, divisibility uint8, expected uint128.Uint128) {
+		d, err := ParseDecimal(s)
+		require.NoError(t, err)
+		value, err := d.Integer(divisibility)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, value, s)
+	}
+	caseFunc("1234.5678", 4, uint128.From64(12345678))
+	caseFunc("1234.5678", 8, uint128.From64(123456780000))
+	caseFunc("1.50", 1, uint128.From64(15))
+	caseFunc("21000000", 8, uint128.From64(2_100_000_000_000_000))
+	caseFunc("3.40282366920938463463374607431768211455", 38, uint128.Max)
+
+	d, _ := ParseDecimal("1.23")
+	_, err := d.Integer(1)
+	assert.ErrorIs(t, err, ErrExcessPrecision)
+	d, _ = ParseDecimal("4")
+	_, err = d.Integer(38)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestDecimalString(t *testing.T) {
+	caseFunc := func(value uint64, scale uint8, expected string) {
+		assert.Equal(t, expected, Decimal{Value: uint128.From64(value), Scale: scale}.String())
+	}
+	caseFunc(0, 0, "0")
+	caseFunc(0, 3, "0")
+	caseFunc(1, 0, "1")
+	caseFunc(1, 1, "0.1")
+	caseFunc(1, 3, "0.001")
+	caseFunc(1000, 3, "1")
+	caseFunc(1100, 3, "1.1")
+	caseFunc(12345678, 4, "1234.5678")
+	assert.Equal(t, "3.40282366920938463463374607431768211455", Decimal{Value: uint128.Max, Scale: 38}.String())
+
+	b, err := json.Marshal(Decimal{Value: uint128.From64(15), Scale: 1})
+	require.NoError(t, err)
+	assert.Equal(t, `"1.5"`, string(b))
+	var d Decimal
+	require.NoError(t, json.Unmarshal([]byte(`"0.25"`), &d))
+	assert.Equal(t, Decimal{Value: uint128.From64(25), Scale: 2}, d)
+}
+
+func TestPile(t *testing.T) {
+	symbol := '$'
+	caseFunc := func(amount uint64, divisibility uint8, symbol *rune, expected string) {
+		assert.Equal(t, expected, Pile{Amount: uint128.From64(amount), Divisibility: divisibility, Symbol: symbol}.String())
+	}
+	caseFunc(0, 0, nil, "0\u00a0¤")
+	caseFunc(25, 0, nil, "25\u00a0¤")
+	caseFunc(0, 1, nil, "0\u00a0¤")
+	caseFunc(1, 1, nil, "0.1\u00a0¤")
+	caseFunc(10, 1, nil, "1\u00a0¤")
+	caseFunc(1010, 2, &symbol, "10.1\u00a0$")
+	caseFunc(1001, 3, &symbol, "1.001\u00a0$")
+
+	entry := &RuneEntry{Divisibility: 2, Symbol: &symbol}
+	pile := entry.Pile(uint128.From64(150))
+	assert.Equal(t, "1.5\u00a0$", pile.String())
+
+	sum, err := pile.Add(entry.Pile(uint128.From64(50)))
+	require.NoError(t, err)
+	assert.Equal(t, uint128.From64(200), sum.Amount)
+	difference, err := pile.Sub(entry.Pile(uint128.From64(50)))
+	require.NoError(t, err)
+	assert.Equal(t, uint128.From64(100), difference.Amount)
+	product, err := pile.Mul64(3)
+	require.NoError(t, err)
+	assert.Equal(t, uint128.From64(450), product.Amount)
+
+	_, err = pile.Sub(entry.Pile(uint128.From64(151)))
+	assert.ErrorIs(t, err, ErrAmountUnderflow)
+	_, err = pile.Add(Pile{Amount: uint128.From64(1)})
+	assert.ErrorIs(t, err, ErrDivisibilityMismatch)
+	_, err = entry.Pile(uint128.Max).Add(entry.Pile(uint128.From64(1)))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = entry.Pile(uint128.Max.Div64(2)).Mul64(3)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
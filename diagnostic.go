@@ -0,0 +1,194 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+)
+
+// Diagnostic is a flaw found while deciphering a runestone, with the place
+// that caused it.
+type Diagnostic struct {
+	Flaw Flaw
+	// Output is the index of the output holding the runestone.
+	Output int
+	// Offset is the byte offset in the payload of the offending integer. For
+	// InvalidScript and Opcode it is the offset of the offending instruction
+	// in the output script instead. It is -1 for SupplyOverflow, which is not
+	// caused by a single integer.
+	Offset int
+	// Integer is the index of the offending integer in the payload, or -1.
+	Integer int
+	// Tag is the field the offending integer belongs to: its tag for fields,
+	// TagBody for edicts. It is nil when there is no such field.
+	Tag *Tag
+	// Value is the offending integer.
+	Value *uint128.Uint128
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "output %d", d.Output)
+	if d.Offset >= 0 {
+		if d.Integer >= 0 {
+			fmt.Fprintf(&b, ", payload byte %d, integer %d", d.Offset, d.Integer)
+		} else {
+			fmt.Fprintf(&b, ", script byte %d", d.Offset)
+		}
+	}
+	fmt.Fprintf(&b, ": %s", d.Flaw)
+	if d.Tag != nil {
+		fmt.Fprintf(&b, ", %s", *d.Tag)
+	}
+	if d.Value != nil {
+		fmt.Fprintf(&b, ", value %s", *d.Value)
+	}
+	return b.String()
+}
+
+// Diagnose deciphers transaction like Decipher, and also returns every flaw
+// found in its runestone instead of only the first one, which is the flaw of
+// a cenotaph. Diagnostics come in the order Decipher checks for flaws.
+func (r *Runestone) Diagnose(transaction *wire.MsgTx) (*Artifact, []Diagnostic, error) {
+	d := &diagnoser{}
+	artifact, err := r.decipher(transaction, MAGIC_NUMBER, d)
+	return artifact, d.diagnostics, err
+}
+
+// diagnoser collects the diagnostics of a decipher. Its methods do nothing on
+// a nil diagnoser, which is what Decipher uses.
+type diagnoser struct {
+	diagnostics []Diagnostic
+	output      int
+	integers    []uint128.Uint128
+	offsets     []int
+}
+
+func (d *diagnoser) setOutput(output int) {
+	if d != nil {
+		d.output = output
+	}
+}
+
+func (d *diagnoser) setIntegers(integers []uint128.Uint128, offsets []int) {
+	if d != nil {
+		d.integers, d.offsets = integers, offsets
+	}
+}
+
+// at adds flaw caused by the integer at index i.
+func (d *diagnoser) at(flaw Flaw, i int, tag *Tag) {
+	value := d.integers[i]
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Flaw:    flaw,
+		Output:  d.output,
+		Offset:  d.offsets[i],
+		Integer: i,
+		Tag:     tag,
+		Value:   &value,
+	})
+}
+
+func (d *diagnoser) script(payload *Payload) {
+	if d == nil {
+		return
+	}
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Flaw:    payload.Invalid,
+		Output:  payload.output,
+		Offset:  payload.offset,
+		Integer: -1,
+	})
+}
+
+func (d *diagnoser) varint(integers []uint128.Uint128, offsets []int) {
+	if d == nil {
+		return
+	}
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Flaw:    Varint,
+		Output:  d.output,
+		Offset:  offsets[len(integers)],
+		Integer: len(integers),
+	})
+}
+
+func (d *diagnoser) message(message *Message, positions *messagePositions) {
+	if d == nil || message.Flaw == nil {
+		return
+	}
+	i := positions.flaw
+	switch *message.Flaw {
+	case TruncatedField:
+		tag := NewTag(d.integers[i])
+		d.at(TruncatedField, i, &tag)
+	default:
+		d.at(*message.Flaw, i, TagP(TagBody))
+	}
+}
+
+func (d *diagnoser) supplyOverflow() {
+	if d == nil {
+		return
+	}
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Flaw:    SupplyOverflow,
+		Output:  d.output,
+		Offset:  -1,
+		Integer: -1,
+	})
+}
+
+func (d *diagnoser) unrecognizedFlag(positions *messagePositions) {
+	if d == nil {
+		return
+	}
+	// the first flags field is the one that was read
+	d.at(UnrecognizedFlag, positions.fields[TagFlags][0]+1, TagP(TagFlags))
+}
+
+// unrecognizedEvenTags adds a diagnostic for every value of an even tag left
+// in fields. Fields are read from the front, so those are the last values
+// of their tag.
+func (d *diagnoser) unrecognizedEvenTags(fields map[Tag][]uint128.Uint128, positions *messagePositions) {
+	if d == nil {
+		return
+	}
+	var indices []int
+	for tag, values := range fields {
+		if tag%2 != 0 {
+			continue
+		}
+		tags := positions.fields[tag]
+		for _, i := range tags[len(tags)-len(values):] {
+			indices = append(indices, i)
+		}
+	}
+	sort.Ints(indices)
+	for _, i := range indices {
+		tag := NewTag(d.integers[i])
+		d.at(UnrecognizedEvenTag, i+1, &tag)
+	}
+}
+
+// TagP returns a pointer to t.
+func TagP(t Tag) *Tag {
+	return &t
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"lukechampine.com/uint128"
+)
+
+// diagnose deciphers a transaction whose second output holds script.
+func diagnose(t *testing.T, script []byte) (*Artifact, []Diagnostic) {
+	tx := &wire.MsgTx{TxOut: []*wire.TxOut{
+		{PkScript: []byte{txscript.OP_TRUE}},
+		{PkScript: script},
+	}}
+	artifact, diagnostics, _ := (&Runestone{}).Diagnose(tx)
+	expected, _ := (&Runestone{}).Decipher(tx)
+	assert.Equal(t, expected, artifact)
+	return artifact, diagnostics
+}
+
+func diagnoseIntegers(t *testing.T, integers ...uint64) (*Artifact, []Diagnostic) {
+	var values []uint128.Uint128
+	for _, integer := range integers {
+		values = append(values, uint128.From64(integer))
+	}
+	return diagnose(t, append([]byte{txscript.OP_RETURN, MAGIC_NUMBER, byte(len(payload(values)))}, payload(values)...))
+}
+
+func diagnostic(flaw Flaw, offset, integer int, tag Tag, value uint64) Diagnostic {
+	v := uint128.From64(value)
+	return Diagnostic{Flaw: flaw, Output: 1, Offset: offset, Integer: integer, Tag: &tag, Value: &v}
+}
+
+func TestDiagnoseReportsEveryFlaw(t *testing.T) {
+	artifact, diagnostics := diagnoseIntegers(t,
+		uint64(TagFlags), 1<<FlagEtching|1<<3,
+		uint64(TagPointer), 9,
+		24, 1,
+		uint64(TagBody), 1, 1, 5, 5,
+	)
+	assert.Equal(t, []Diagnostic{
+		diagnostic(EdictOutput, 10, 10, TagBody, 5),
+		diagnostic(UnrecognizedFlag, 1, 1, TagFlags, 1<<FlagEtching|1<<3),
+		diagnostic(UnrecognizedEvenTag, 3, 3, TagPointer, 9),
+		diagnostic(UnrecognizedEvenTag, 5, 5, 24, 1),
+	}, diagnostics)
+	// the cenotaph keeps the first flaw, as ord does
+	if assert.NotNil(t, artifact.Cenotaph) {
+		assert.Equal(t, FlawP(EdictOutput), artifact.Cenotaph.Flaw)
+	}
+}
+
+func TestDiagnoseOffsets(t *testing.T) {
+	// without an etching flag both runes are left unread
+	_, diagnostics := diagnoseIntegers(t, uint64(TagRune), 300, uint64(TagRune), 300000)
+	assert.Equal(t, []Diagnostic{
+		diagnostic(UnrecognizedEvenTag, 1, 1, TagRune, 300),
+		diagnostic(UnrecognizedEvenTag, 4, 3, TagRune, 300000),
+	}, diagnostics)
+
+	_, diagnostics = diagnoseIntegers(t, uint64(TagMint), 1, uint64(TagPointer))
+	assert.Equal(t, []Diagnostic{
+		diagnostic(TruncatedField, 2, 2, TagPointer, uint64(TagPointer)),
+		// a mint needs two values
+		diagnostic(UnrecognizedEvenTag, 1, 1, TagMint, 1),
+	}, diagnostics)
+
+	_, diagnostics = diagnoseIntegers(t, uint64(TagBody), 1, 1, 1)
+	assert.Equal(t, []Diagnostic{diagnostic(TrailingIntegers, 1, 1, TagBody, 1)}, diagnostics)
+
+	_, diagnostics = diagnose(t, []byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_DATA_2, byte(TagPointer), 0x80})
+	assert.Equal(t, []Diagnostic{{Flaw: Varint, Output: 1, Offset: 1, Integer: 1}}, diagnostics)
+
+	_, diagnostics = diagnose(t, []byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_DATA_1, 0, txscript.OP_VERIFY})
+	assert.Equal(t, []Diagnostic{{Flaw: Opcode, Output: 1, Offset: 4, Integer: -1}}, diagnostics)
+}
+
+func TestDiagnoseSupplyOverflow(t *testing.T) {
+	max := uint128.Max
+	script, err := (&Runestone{Etching: &Etching{
+		Premine: &max,
+		Terms:   &Terms{Amount: Uint128PFrom64(1), Cap: Uint128PFrom64(1)},
+	}}).Encipher()
+	assert.NoError(t, err)
+	_, diagnostics := diagnose(t, script)
+	assert.Equal(t, []Diagnostic{{Flaw: SupplyOverflow, Output: 1, Offset: -1, Integer: -1}}, diagnostics)
+	assert.Equal(t, "output 1: supply overflows u128", diagnostics[0].String())
+}
+
+func TestDiagnoseValidRunestone(t *testing.T) {
+	script, _ := (&Runestone{Pointer: Uint32P(0)}).Encipher()
+	artifact, diagnostics := diagnose(t, script)
+	assert.NotNil(t, artifact.Runestone)
+	assert.Empty(t, diagnostics)
+	assert.Equal(t, "output 1, payload byte 3, integer 2: unrecognized even tag, Tag(24), value 1",
+		diagnostic(UnrecognizedEvenTag, 3, 2, 24, 1).String())
+}
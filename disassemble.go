@@ -0,0 +1,217 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+)
+
+// Disassemble returns an annotated listing of a runestone output script: its
+// instructions, then every integer of the payload with its byte offset, its
+// encoding and what Decipher reads it as.
+//
+//	OP_RETURN
+//	OP_13
+//	OP_DATA_4 14011400
+//	0000  14  Mint
+//	0001  01  block 1
+//	0002  14  Mint
+//	0003  00  tx 0
+func Disassemble(pkScript []byte) (string, error) {
+	var b strings.Builder
+	if err := disassemble(&b, pkScript, -1); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// DisassembleTx returns the listing of the runestone output of tx, as
+// Disassemble does, followed by the flaws that make it a cenotaph, if any.
+func DisassembleTx(tx *wire.MsgTx) (string, error) {
+	script := runestoneScript(tx, MAGIC_NUMBER)
+	if script == nil {
+		return "", ErrNoRunestone
+	}
+	var b strings.Builder
+	if err := disassemble(&b, script, len(tx.TxOut)); err != nil {
+		return "", err
+	}
+	artifact, diagnostics, _ := (&Runestone{}).Diagnose(tx)
+	if artifact != nil && artifact.Cenotaph != nil {
+		b.WriteString("cenotaph\n")
+	}
+	for _, d := range diagnostics {
+		fmt.Fprintf(&b, "flaw: %s\n", d)
+	}
+	return b.String(), nil
+}
+
+// disassemble writes the listing of script. outputs is the number of outputs
+// of its transaction, used to check edict outputs, or -1 if unknown.
+func disassemble(b *strings.Builder, script []byte, outputs int) error {
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	if !tokenizer.Next() || tokenizer.Opcode() != txscript.OP_RETURN ||
+		!tokenizer.Next() || tokenizer.Opcode() != MAGIC_NUMBER {
+		return ErrNoRunestone
+	}
+	b.WriteString("OP_RETURN\nOP_13\n")
+
+	var payload []byte
+	start := tokenizer.ByteIndex()
+	for tokenizer.Next() {
+		opcode := tokenizer.Opcode()
+		if !isPushBytes(opcode) {
+			fmt.Fprintf(b, "%s  ; non-pushdata opcode, cenotaph\n", opcodeName(script[start:tokenizer.ByteIndex()]))
+			break
+		}
+		fmt.Fprintf(b, "%s\n", opcodeName(script[start:tokenizer.ByteIndex()]))
+		payload = append(payload, tokenizer.Data()...)
+		start = tokenizer.ByteIndex()
+	}
+	if err := tokenizer.Err(); err != nil {
+		fmt.Fprintf(b, "%x  ; invalid script, cenotaph\n", script[start:])
+	}
+
+	integers, offsets, err := decodeIntegers(payload)
+	line := func(i int, note string, args ...any) {
+		fmt.Fprintf(b, "%04d  %-*x  %s\n", offsets[i], 2*maxVarintLength(offsets), payload[offsets[i]:offsets[i+1]], fmt.Sprintf(note, args...))
+	}
+
+	counts := make(map[Tag]int)
+	for i := 0; i < len(integers); i += 2 {
+		tag := Tag(integers[i].Lo)
+		if tag == TagBody {
+			line(i, "%s", tag)
+			disassembleEdicts(line, integers, i+1, outputs)
+			break
+		}
+		line(i, "%s", tagNote(integers[i]))
+		if i+1 == len(integers) {
+			b.WriteString("      ; truncated field\n")
+			break
+		}
+		line(i+1, "%s", valueNote(tag, counts[tag], integers[i+1]))
+		counts[tag]++
+	}
+	if err != nil {
+		fmt.Fprintf(b, "%04d  %x  ; invalid varint: %v\n", offsets[len(integers)], payload[offsets[len(integers)]:], err)
+	}
+	return nil
+}
+
+func disassembleEdicts(line func(int, string, ...any), integers []uint128.Uint128, start, outputs int) {
+	id := RuneId{}
+	for j := start; j < len(integers); j += 4 {
+		if j+3 >= len(integers) {
+			for ; j < len(integers); j++ {
+				line(j, "%s  ; trailing integer", integers[j])
+			}
+			return
+		}
+		next, err := id.Next(integers[j], integers[j+1])
+		if err != nil {
+			line(j, "block +%s  ; invalid rune id", integers[j])
+			line(j+1, "tx +%s", integers[j+1])
+			return
+		}
+		line(j, "block +%s", integers[j])
+		line(j+1, "tx %s -> id %s", integers[j+1], next)
+		line(j+2, "amount %s", integers[j+2])
+		if outputs >= 0 && integers[j+3].Cmp64(uint64(outputs)) > 0 {
+			line(j+3, "output %s  ; greater than output count", integers[j+3])
+			return
+		}
+		if outputs >= 0 && integers[j+3].Cmp64(uint64(outputs)) == 0 {
+			line(j+3, "output %s, split across outputs", integers[j+3])
+		} else {
+			line(j+3, "output %s", integers[j+3])
+		}
+		id = *next
+	}
+}
+
+func tagNote(tag uint128.Uint128) string {
+	if tag.Hi == 0 && tag.Lo <= 0xff {
+		if _, ok := tagToName[Tag(tag.Lo)]; ok {
+			return Tag(tag.Lo).String()
+		}
+	}
+	if tag.Lo%2 == 0 {
+		return fmt.Sprintf("Tag(%s)  ; unrecognized even tag, cenotaph", tag)
+	}
+	return fmt.Sprintf("Tag(%s)  ; unrecognized odd tag, ignored", tag)
+}
+
+// valueNote describes the n-th value of tag.
+func valueNote(tag Tag, n int, value uint128.Uint128) string {
+	note := value.String()
+	switch tag {
+	case TagFlags:
+		var names []string
+		for bit := uint(0); bit < 128; bit++ {
+			if !value.Rsh(bit).And64(1).IsZero() {
+				names = append(names, Flag(bit).String())
+			}
+		}
+		note = fmt.Sprintf("%#b %s", value.Big(), strings.Join(names, " | "))
+	case TagRune:
+		note = fmt.Sprintf("%s %s", value, Rune{Value: value})
+	case TagSymbol:
+		note = fmt.Sprintf("%s %q", value, rune(value.Lo))
+	case TagMint:
+		if n%2 == 0 {
+			note = "block " + note
+		} else {
+			note = "tx " + note
+		}
+	}
+	return note
+}
+
+// opcodeName disassembles a single instruction, naming data pushes by their
+// opcode rather than just printing their data as txscript does.
+func opcodeName(instruction []byte) string {
+	opcode := instruction[0]
+	switch {
+	case opcode == txscript.OP_0:
+		return "OP_0"
+	case opcode < txscript.OP_PUSHDATA1:
+		return fmt.Sprintf("OP_DATA_%d %x", opcode, instruction[1:])
+	case opcode == txscript.OP_PUSHDATA1:
+		return fmt.Sprintf("OP_PUSHDATA1 %x", instruction[2:])
+	case opcode == txscript.OP_PUSHDATA2:
+		return fmt.Sprintf("OP_PUSHDATA2 %x", instruction[3:])
+	case opcode == txscript.OP_PUSHDATA4:
+		return fmt.Sprintf("OP_PUSHDATA4 %x", instruction[5:])
+	}
+	name, err := txscript.DisasmString(instruction)
+	if err != nil {
+		return fmt.Sprintf("%x", instruction)
+	}
+	return name
+}
+
+func maxVarintLength(offsets []int) int {
+	length := 1
+	for i := 1; i < len(offsets); i++ {
+		length = max(length, offsets[i]-offsets[i-1])
+	}
+	return length
+}
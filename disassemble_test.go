@@ -0,0 +1,99 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+func TestDisassembleMint(t *testing.T) {
+	listing, err := Disassemble([]byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_DATA_4, 0x14, 0x01, 0x14, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, "OP_RETURN\n"+
+		"OP_13\n"+
+		"OP_DATA_4 14011400\n"+
+		"0000  14  Mint\n"+
+		"0001  01  block 1\n"+
+		"0002  14  Mint\n"+
+		"0003  00  tx 0\n", listing)
+}
+
+func TestDisassembleEtchingAndEdicts(t *testing.T) {
+	symbol := 'x'
+	r := Rune{Value: uint128.From64(3)}
+	script, err := (&Runestone{
+		Etching: &Etching{Rune: &r, Symbol: &symbol},
+		Edicts: []Edict{
+			{ID: RuneId{Block: 840000, Tx: 3}, Amount: uint128.From64(5), Output: 0},
+			{ID: RuneId{Block: 840000, Tx: 7}, Amount: uint128.From64(6), Output: 1},
+		},
+	}).Encipher()
+	require.NoError(t, err)
+
+	listing, err := Disassemble(script)
+	require.NoError(t, err)
+	assert.Contains(t, listing, "0000  02      Flags\n")
+	assert.Contains(t, listing, "0001  01      0b1 Etching\n")
+	assert.Contains(t, listing, "  03      3 D\n")
+	assert.Contains(t, listing, "  78      120 'x'\n")
+	assert.Contains(t, listing, "  c0a233  block +840000\n")
+	assert.Contains(t, listing, "  03      tx 3 -> id 840000:3\n")
+	assert.Contains(t, listing, "  00      block +0\n")
+	assert.Contains(t, listing, "  04      tx 4 -> id 840000:7\n")
+	assert.Contains(t, listing, "  01      output 1\n")
+}
+
+func TestDisassembleUnknownData(t *testing.T) {
+	// an unrecognized odd tag, an unrecognized even tag, unknown flag bits and
+	// a truncated field
+	listing, err := Disassemble([]byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_DATA_7, 0x09, 0x00, 0x18, 0x00, 0x02, 0x30, 0x16})
+	require.NoError(t, err)
+	assert.Contains(t, listing, "0000  09  Tag(9)  ; unrecognized odd tag, ignored\n")
+	assert.Contains(t, listing, "0002  18  Tag(24)  ; unrecognized even tag, cenotaph\n")
+	assert.Contains(t, listing, "0005  30  0b110000 Flag(4) | Flag(5)\n")
+	assert.Contains(t, listing, "0006  16  Pointer\n      ; truncated field\n")
+
+	listing, err = Disassemble([]byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_DATA_5, 0x00, 0x01, 0x02, 0x03, 0x80})
+	require.NoError(t, err)
+	assert.Contains(t, listing, "0001  01  1  ; trailing integer\n")
+	assert.Contains(t, listing, "0004  80  ; invalid varint: ")
+
+	listing, err = Disassemble([]byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_VERIFY})
+	require.NoError(t, err)
+	assert.Contains(t, listing, "OP_VERIFY  ; non-pushdata opcode, cenotaph\n")
+
+	_, err = Disassemble([]byte{txscript.OP_RETURN, txscript.OP_DATA_1, 0x00})
+	assert.ErrorIs(t, err, ErrNoRunestone)
+}
+
+func TestDisassembleTx(t *testing.T) {
+	tx := &wire.MsgTx{TxOut: []*wire.TxOut{
+		{PkScript: []byte{txscript.OP_TRUE}},
+		{PkScript: []byte{txscript.OP_RETURN, MAGIC_NUMBER, txscript.OP_DATA_5, 0x00, 0x01, 0x00, 0x01, 0x05}},
+	}}
+	listing, err := DisassembleTx(tx)
+	require.NoError(t, err)
+	assert.Contains(t, listing, "0004  05  output 5  ; greater than output count\n")
+	assert.Contains(t, listing, "cenotaph\nflaw: ")
+
+	_, err = DisassembleTx(&wire.MsgTx{TxOut: []*wire.TxOut{{PkScript: []byte{txscript.OP_TRUE}}}})
+	assert.ErrorIs(t, err, ErrNoRunestone)
+}
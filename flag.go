@@ -14,7 +14,11 @@
 
 package runestone
 
-import "lukechampine.com/uint128"
+import (
+	"fmt"
+
+	"lukechampine.com/uint128"
+)
 
 type Flag uint8
 
@@ -25,6 +29,20 @@ const (
 	FlagTurbo    Flag = 2
 )
 
+var flagToName = map[Flag]string{
+	FlagEtching:  "Etching",
+	FlagTerms:    "Terms",
+	FlagCenotaph: "Cenotaph",
+	FlagTurbo:    "Turbo",
+}
+
+func (f Flag) String() string {
+	if name, ok := flagToName[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Flag(%d)", f)
+}
+
 func (f Flag) Mask() uint128.Uint128 {
 	return uint128.From64(1).Lsh(uint(f))
 }
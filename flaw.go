@@ -14,7 +14,10 @@
 
 package runestone
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 type Flaw int
 
@@ -48,6 +51,20 @@ var flawToString = map[Flaw]string{
 	Varint:              "invalid varint",
 }
 
+// flawToIdentifier holds the names ord serializes flaws with.
+var flawToIdentifier = map[Flaw]string{
+	EdictOutput:         "edict_output",
+	EdictRuneId:         "edict_rune_id",
+	InvalidScript:       "invalid_script",
+	Opcode:              "opcode",
+	SupplyOverflow:      "supply_overflow",
+	TrailingIntegers:    "trailing_integers",
+	TruncatedField:      "truncated_field",
+	UnrecognizedEvenTag: "unrecognized_even_tag",
+	UnrecognizedFlag:    "unrecognized_flag",
+	Varint:              "varint",
+}
+
 func (f Flaw) String() string {
 	return flawToString[f]
 }
@@ -62,3 +79,23 @@ func NewFlaw(s string) Flaw {
 	}
 	return -1
 }
+
+// MarshalText encodes f as a stable identifier, such as "edict_output".
+func (f Flaw) MarshalText() ([]byte, error) {
+	identifier, ok := flawToIdentifier[f]
+	if !ok {
+		return nil, fmt.Errorf("unknown flaw %d", int(f))
+	}
+	return []byte(identifier), nil
+}
+
+// UnmarshalText decodes a flaw identifier.
+func (f *Flaw) UnmarshalText(text []byte) error {
+	for k, v := range flawToIdentifier {
+		if v == string(text) {
+			*f = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown flaw %q", text)
+}
@@ -0,0 +1,441 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpapi serves a read-only JSON API over a local rune index. The
+// routes and response shapes follow ord's JSON API (/rune/:rune, /runes,
+// /output/:outpoint, /address/:address and /decode/:txid), so clients written
+// for ord can point at it. As everywhere in this module, uint128 amounts are
+// encoded as decimal strings.
+package httpapi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/bxelab/runestone/index"
+)
+
+// RunesPageSize is the number of entries on a page of /runes, as in ord.
+const RunesPageSize = 50
+
+// TxSource fetches transactions, which the index does not keep. It is needed
+// by /decode and fills in the value, script and address of outputs.
+type TxSource interface {
+	RawTransaction(txid *chainhash.Hash) (*wire.MsgTx, error)
+}
+
+// Server is an http.Handler answering queries from an index.
+type Server struct {
+	index *index.Index
+	txs   TxSource
+	mux   *http.ServeMux
+}
+
+// NewServer returns a Server for idx. txs may be nil, in which case /decode is
+// unavailable and outputs are described by their runes only.
+func NewServer(idx *index.Index, txs TxSource) *Server {
+	s := &Server{index: idx, txs: txs, mux: http.NewServeMux()}
+	s.handle("GET /rune/{rune}", s.rune)
+	s.handle("GET /runes", s.runes)
+	s.handle("GET /runes/{page}", s.runes)
+	s.handle("GET /output/{outpoint}", s.output)
+	s.handle("GET /address/{address}", s.address)
+	s.handle("GET /decode/{txid}", s.decode)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// statusError is an error answered with its status code instead of 500.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func notFound(format string, args ...any) error {
+	return &statusError{status: http.StatusNotFound, msg: fmt.Sprintf(format, args...)}
+}
+
+func badRequest(format string, args ...any) error {
+	return &statusError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) handle(pattern string, fn func(r *http.Request) (any, error)) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		v, err := fn(r)
+		var body []byte
+		if err == nil {
+			// encode before writing anything, so that a failure can still
+			// be answered with an error status
+			body, err = json.Marshal(v)
+		}
+		if err != nil {
+			var se *statusError
+			if errors.As(err, &se) {
+				http.Error(w, se.msg, se.status)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(body, '\n'))
+	})
+}
+
+type runeJSON struct {
+	Entry    *runestone.RuneEntry `json:"entry"`
+	ID       runestone.RuneId     `json:"id"`
+	Mintable bool                 `json:"mintable"`
+	Parent   *string              `json:"parent"`
+}
+
+// rune answers /rune/:rune, where the rune is given by id or spaced name.
+func (s *Server) rune(r *http.Request) (any, error) {
+	query := r.PathValue("rune")
+	var id *runestone.RuneId
+	var err error
+	if strings.Contains(query, ":") {
+		id, err = runestone.RuneIdFromString(query)
+		if err != nil {
+			return nil, badRequest("invalid rune id %q: %v", query, err)
+		}
+	} else {
+		spaced, err := runestone.SpacedRuneFromString(query)
+		if err != nil {
+			return nil, badRequest("invalid rune %q: %v", query, err)
+		}
+		id, err = s.index.RuneId(spaced.Rune)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var entry *runestone.RuneEntry
+	if id != nil {
+		entry, err = s.index.RuneEntry(*id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if entry == nil {
+		return nil, notFound("rune %s not found", query)
+	}
+	_, err = entry.Mintable(s.index.NextHeight())
+	return runeJSON{Entry: entry, ID: *id, Mintable: err == nil}, nil
+}
+
+// runeEntryJSON is a [RuneId, RuneEntry] pair of /runes.
+type runeEntryJSON struct {
+	ID    runestone.RuneId
+	Entry *runestone.RuneEntry
+}
+
+func (e runeEntryJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.ID, e.Entry})
+}
+
+type runesJSON struct {
+	Entries []runeEntryJSON `json:"entries"`
+	More    bool            `json:"more"`
+	Prev    *int            `json:"prev"`
+	Next    *int            `json:"next"`
+}
+
+// runes answers /runes and /runes/:page, listing the newest runes first.
+// Pages past the last one are rejected, so a page number cannot make the
+// server hold more than a page of entries.
+func (s *Server) runes(r *http.Request) (any, error) {
+	total := s.index.Runes()
+	page := uint64(0)
+	if p := r.PathValue("page"); p != "" {
+		var err error
+		page, err = strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid page %q", p)
+		}
+		if page > 0 && page >= (total+RunesPageSize-1)/RunesPageSize {
+			return nil, badRequest("page %d is past the last page", page)
+		}
+	}
+	// entries are kept in id order, which is the order they were etched in,
+	// so the page is the range [first, end) of that order
+	end := total - page*RunesPageSize
+	first := uint64(0)
+	if end > RunesPageSize {
+		first = end - RunesPageSize
+	}
+	entries := make([]runeEntryJSON, 0, end-first)
+	n := uint64(0)
+	err := s.index.ForEachRuneEntry(func(id runestone.RuneId, entry *runestone.RuneEntry) error {
+		if n >= end {
+			return errPageDone
+		}
+		if n >= first {
+			entries = append(entries, runeEntryJSON{ID: id, Entry: entry})
+		}
+		n++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPageDone) {
+		return nil, err
+	}
+	result := runesJSON{Entries: make([]runeEntryJSON, 0, len(entries))}
+	for i := len(entries) - 1; i >= 0; i-- {
+		result.Entries = append(result.Entries, entries[i])
+	}
+	result.More = first > 0
+	if page > 0 {
+		prev := int(page - 1)
+		result.Prev = &prev
+	}
+	if result.More {
+		next := int(page + 1)
+		result.Next = &next
+	}
+	return result, nil
+}
+
+// errPageDone stops the iteration over rune entries at the end of a page.
+var errPageDone = errors.New("page done")
+
+type pileJSON struct {
+	Amount       string  `json:"amount"`
+	Divisibility uint8   `json:"divisibility"`
+	Symbol       *string `json:"symbol"`
+}
+
+type outputJSON struct {
+	Address      *string             `json:"address"`
+	Outpoint     string              `json:"outpoint"`
+	Indexed      bool                `json:"indexed"`
+	Inscriptions []string            `json:"inscriptions"`
+	Runes        map[string]pileJSON `json:"runes"`
+	SatRanges    [][2]uint64         `json:"sat_ranges"`
+	ScriptPubkey string              `json:"script_pubkey"`
+	Spent        bool                `json:"spent"`
+	Transaction  string              `json:"transaction"`
+	Value        int64               `json:"value"`
+}
+
+// output answers /output/:outpoint. The index only keeps unspent outputs
+// holding runes, so spent is always false and inscriptions and sat ranges are
+// not known. Indexed tells whether the index holds the output; the value,
+// script and address come from the TxSource when it can provide them.
+func (s *Server) output(r *http.Request) (any, error) {
+	outpoint, err := wire.NewOutPointFromString(r.PathValue("outpoint"))
+	if err != nil {
+		return nil, badRequest("invalid outpoint %q: %v", r.PathValue("outpoint"), err)
+	}
+	balances, err := s.index.Balances(*outpoint)
+	if err != nil {
+		return nil, err
+	}
+	result := outputJSON{
+		Outpoint:    outpoint.String(),
+		Indexed:     balances != nil,
+		Runes:       map[string]pileJSON{},
+		Transaction: outpoint.Hash.String(),
+	}
+	for _, balance := range balances {
+		entry, err := s.index.RuneEntry(balance.ID)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("rune %s of output %s not found", balance.ID, outpoint)
+		}
+		result.Runes[entry.SpacedRune.String()] = newPileJSON(entry.Pile(balance.Amount))
+	}
+	if s.txs != nil {
+		out, err := s.txOut(*outpoint)
+		if err != nil {
+			// the tx source only adds to what the index knows
+			if result.Indexed {
+				return result, nil
+			}
+			return nil, err
+		}
+		result.Value = out.Value
+		result.ScriptPubkey = hex.EncodeToString(out.PkScript)
+		result.Address = s.scriptAddress(out.PkScript)
+	}
+	return result, nil
+}
+
+func newPileJSON(p runestone.Pile) pileJSON {
+	v := pileJSON{Amount: p.Amount.String(), Divisibility: p.Divisibility}
+	if p.Symbol != nil {
+		symbol := string(*p.Symbol)
+		v.Symbol = &symbol
+	}
+	return v
+}
+
+func (s *Server) txOut(outpoint wire.OutPoint) (*wire.TxOut, error) {
+	tx, err := s.txs.RawTransaction(&outpoint.Hash)
+	if err != nil {
+		return nil, notFound("output %s not found: %v", outpoint, err)
+	}
+	if outpoint.Index >= uint32(len(tx.TxOut)) {
+		return nil, notFound("output %s not found", outpoint)
+	}
+	return tx.TxOut[outpoint.Index], nil
+}
+
+func (s *Server) scriptAddress(pkScript []byte) *string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, s.index.Chain().Params)
+	if err != nil || len(addrs) != 1 {
+		return nil
+	}
+	address := addrs[0].EncodeAddress()
+	return &address
+}
+
+// runeBalanceJSON is a [spaced rune, decimal amount, symbol] triple of
+// /address.
+type runeBalanceJSON struct {
+	Rune   runestone.SpacedRune
+	Amount runestone.Decimal
+	Symbol *string
+}
+
+func (b runeBalanceJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{b.Rune, b.Amount, b.Symbol})
+}
+
+type addressJSON struct {
+	Outputs       []string          `json:"outputs"`
+	Inscriptions  []string          `json:"inscriptions"`
+	SatBalance    int64             `json:"sat_balance"`
+	RunesBalances []runeBalanceJSON `json:"runes_balances"`
+}
+
+// address answers /address/:address with the outputs of the address that
+// hold runes and their total per rune. The sat balance counts those outputs
+// only, and only if there is a TxSource.
+func (s *Server) address(r *http.Request) (any, error) {
+	params := s.index.Chain().Params
+	addr, err := btcutil.DecodeAddress(r.PathValue("address"), params)
+	if err != nil || !addr.IsForNet(params) {
+		return nil, badRequest("invalid address %q", r.PathValue("address"))
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, badRequest("invalid address %q: %v", r.PathValue("address"), err)
+	}
+	outpoints, err := s.index.Outpoints(pkScript)
+	if err != nil {
+		return nil, err
+	}
+	result := addressJSON{Outputs: []string{}, RunesBalances: []runeBalanceJSON{}}
+	totals := make(runestone.Balances)
+	for _, outpoint := range outpoints {
+		result.Outputs = append(result.Outputs, outpoint.String())
+		balances, err := s.index.Balances(outpoint)
+		if err != nil {
+			return nil, err
+		}
+		for _, balance := range balances {
+			totals.Add(balance.ID, balance.Amount)
+		}
+		if s.txs != nil {
+			out, err := s.txOut(outpoint)
+			if err != nil {
+				return nil, err
+			}
+			result.SatBalance += out.Value
+		}
+	}
+	ids := make([]runestone.RuneId, 0, len(totals))
+	for id := range totals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Block != ids[j].Block {
+			return ids[i].Block < ids[j].Block
+		}
+		return ids[i].Tx < ids[j].Tx
+	})
+	for _, id := range ids {
+		entry, err := s.index.RuneEntry(id)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("rune %s not found", id)
+		}
+		b := runeBalanceJSON{Rune: entry.SpacedRune, Amount: entry.Pile(totals[id]).Decimal()}
+		if entry.Symbol != nil {
+			symbol := string(*entry.Symbol)
+			b.Symbol = &symbol
+		}
+		result.RunesBalances = append(result.RunesBalances, b)
+	}
+	return result, nil
+}
+
+// artifactJSON encodes an artifact like ord, tagged with its variant.
+type artifactJSON struct {
+	*runestone.Artifact
+}
+
+func (a artifactJSON) MarshalJSON() ([]byte, error) {
+	if a.Cenotaph != nil {
+		return json.Marshal(map[string]any{"Cenotaph": a.Cenotaph})
+	}
+	return json.Marshal(map[string]any{"Runestone": a.Runestone})
+}
+
+type decodeJSON struct {
+	Inscriptions []any         `json:"inscriptions"`
+	Runestone    *artifactJSON `json:"runestone"`
+}
+
+// decode answers /decode/:txid with the runestone of the transaction. Unlike
+// ord, inscriptions are not decoded.
+func (s *Server) decode(r *http.Request) (any, error) {
+	if s.txs == nil {
+		return nil, &statusError{status: http.StatusNotImplemented, msg: "no transaction source"}
+	}
+	txid, err := chainhash.NewHashFromStr(r.PathValue("txid"))
+	if err != nil {
+		return nil, badRequest("invalid txid %q: %v", r.PathValue("txid"), err)
+	}
+	tx, err := s.txs.RawTransaction(txid)
+	if err != nil {
+		return nil, notFound("transaction %s not found: %v", txid, err)
+	}
+	result := decodeJSON{Inscriptions: []any{}}
+	if artifact, _ := s.index.Chain().Decipher(tx); artifact != nil {
+		result.Runestone = &artifactJSON{artifact}
+	}
+	return result, nil
+}
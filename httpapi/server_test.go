@@ -0,0 +1,269 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/bxelab/runestone/blocksource"
+	"github.com/bxelab/runestone/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+// Every block source can serve transactions.
+var _ TxSource = blocksource.BlockSource(nil)
+
+type mockTxs map[chainhash.Hash]*wire.MsgTx
+
+func (m mockTxs) RawTransaction(txid *chainhash.Hash) (*wire.MsgTx, error) {
+	tx, ok := m[*txid]
+	if !ok {
+		return nil, errors.New("unknown transaction")
+	}
+	return tx, nil
+}
+
+type testServer struct {
+	t       *testing.T
+	index   *index.Index
+	txs     mockTxs
+	server  *Server
+	address btcutil.Address
+	script  []byte
+}
+
+func newTestServer(t *testing.T) *testServer {
+	idx, err := index.NewIndex(index.NewMemoryStore(), runestone.Regtest, nil)
+	require.NoError(t, err)
+	address, err := btcutil.NewAddressTaproot(make([]byte, 32), runestone.Regtest.Params)
+	require.NoError(t, err)
+	script, err := txscript.PayToAddrScript(address)
+	require.NoError(t, err)
+	txs := mockTxs{}
+	s := &testServer{t: t, index: idx, txs: txs, server: NewServer(idx, txs), address: address, script: script}
+	s.etch() // rune ids cannot refer to the genesis block
+	return s
+}
+
+// etch mines a block with an unnamed etching for each premine, paid to the
+// address of the server, and returns the etching transactions.
+func (s *testServer) etch(premines ...uint64) []*wire.MsgTx {
+	height := s.index.NextHeight()
+	block := &wire.MsgBlock{Header: wire.BlockHeader{Timestamp: time.Unix(int64(height), 0)}}
+	if height > 0 {
+		prev, err := s.index.BlockHash(height - 1)
+		require.NoError(s.t, err)
+		block.Header.PrevBlock = *prev
+	}
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex}, []byte{byte(height)}, nil))
+	block.AddTransaction(coinbase)
+	var etchings []*wire.MsgTx
+	for i, premine := range premines {
+		symbol, divisibility := '$', uint8(1)
+		script, err := runestone.Regtest.Encipher(&runestone.Runestone{Etching: &runestone.Etching{
+			Divisibility: &divisibility,
+			Premine:      u128P(premine),
+			Symbol:       &symbol,
+			Terms:        &runestone.Terms{Amount: u128P(1), Cap: u128P(1)},
+		}})
+		require.NoError(s.t, err)
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{byte(height), byte(i), 1}}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(0, script))
+		tx.AddTxOut(wire.NewTxOut(546, s.script))
+		block.AddTransaction(tx)
+		s.txs[tx.TxHash()] = tx
+		etchings = append(etchings, tx)
+	}
+	require.NoError(s.t, s.index.IndexBlock(height, block))
+	return etchings
+}
+
+func (s *testServer) get(path string, v any) int {
+	rec := httptest.NewRecorder()
+	s.server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code == http.StatusOK && v != nil {
+		require.NoError(s.t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	return rec.Code
+}
+
+func TestRune(t *testing.T) {
+	s := newTestServer(t)
+	s.etch(15)
+	id := runestone.RuneId{Block: 1, Tx: 1}
+	name := runestone.Reserved(1, 1).String()
+
+	var byId, byName struct {
+		Entry    runestone.RuneEntry `json:"entry"`
+		ID       runestone.RuneId    `json:"id"`
+		Mintable bool                `json:"mintable"`
+		Parent   *string             `json:"parent"`
+	}
+	require.Equal(t, http.StatusOK, s.get("/rune/1:1", &byId))
+	assert.Equal(t, id, byId.ID)
+	assert.Equal(t, name, byId.Entry.SpacedRune.String())
+	assert.Equal(t, uint128.From64(15), byId.Entry.Premine)
+	assert.True(t, byId.Mintable)
+	assert.Nil(t, byId.Parent)
+
+	require.Equal(t, http.StatusOK, s.get("/rune/"+name, &byName))
+	assert.Equal(t, byId, byName)
+
+	assert.Equal(t, http.StatusNotFound, s.get("/rune/1:2", nil))
+	assert.Equal(t, http.StatusNotFound, s.get("/rune/AAAAAAAAAAAAA", nil))
+	assert.Equal(t, http.StatusBadRequest, s.get("/rune/1:x", nil))
+	assert.Equal(t, http.StatusBadRequest, s.get("/rune/abc", nil))
+}
+
+func TestRunesPages(t *testing.T) {
+	s := newTestServer(t)
+	premines := make([]uint64, RunesPageSize+1)
+	s.etch(premines...)
+
+	type page struct {
+		Entries [][2]json.RawMessage `json:"entries"`
+		More    bool                 `json:"more"`
+		Prev    *int                 `json:"prev"`
+		Next    *int                 `json:"next"`
+	}
+	id := func(raw json.RawMessage) string {
+		var id runestone.RuneId
+		require.NoError(t, json.Unmarshal(raw, &id))
+		return id.String()
+	}
+	var first, second page
+	require.Equal(t, http.StatusOK, s.get("/runes", &first))
+	assert.Len(t, first.Entries, RunesPageSize)
+	assert.Equal(t, fmt.Sprintf("1:%d", RunesPageSize+1), id(first.Entries[0][0]))
+	assert.True(t, first.More)
+	assert.Nil(t, first.Prev)
+	assert.Equal(t, 1, *first.Next)
+
+	require.Equal(t, http.StatusOK, s.get("/runes/1", &second))
+	if assert.Len(t, second.Entries, 1) {
+		assert.Equal(t, "1:1", id(second.Entries[0][0]))
+	}
+	assert.False(t, second.More)
+	assert.Equal(t, 0, *second.Prev)
+	assert.Nil(t, second.Next)
+
+	assert.Equal(t, http.StatusBadRequest, s.get("/runes/-1", nil))
+	assert.Equal(t, http.StatusBadRequest, s.get("/runes/2", nil))
+	assert.Equal(t, http.StatusBadRequest, s.get("/runes/1000000000", nil))
+	assert.Equal(t, http.StatusBadRequest, s.get("/runes/9223372036854775807", nil))
+	assert.Equal(t, http.StatusBadRequest, s.get("/runes/99999999999999999999", nil))
+}
+
+func TestRunesOfEmptyIndex(t *testing.T) {
+	idx, err := index.NewIndex(index.NewMemoryStore(), runestone.Regtest, nil)
+	require.NoError(t, err)
+	s := &testServer{t: t, index: idx, server: NewServer(idx, nil)}
+	var page struct {
+		Entries []json.RawMessage `json:"entries"`
+		More    bool              `json:"more"`
+	}
+	require.Equal(t, http.StatusOK, s.get("/runes", &page))
+	assert.Empty(t, page.Entries)
+	assert.NotNil(t, page.Entries)
+	assert.False(t, page.More)
+	assert.Equal(t, http.StatusBadRequest, s.get("/runes/1", nil))
+}
+
+func TestOutputAndAddress(t *testing.T) {
+	s := newTestServer(t)
+	etchings := s.etch(15, 20)
+	name := runestone.Reserved(1, 1).String()
+
+	var output outputJSON
+	require.Equal(t, http.StatusOK, s.get(fmt.Sprintf("/output/%s:1", etchings[0].TxHash()), &output))
+	assert.Equal(t, map[string]pileJSON{name: {Amount: "15", Divisibility: 1, Symbol: strP("$")}}, output.Runes)
+	assert.Equal(t, int64(546), output.Value)
+	assert.Equal(t, s.address.EncodeAddress(), *output.Address)
+	assert.Equal(t, fmt.Sprintf("%x", s.script), output.ScriptPubkey)
+	assert.True(t, output.Indexed)
+	assert.Equal(t, http.StatusNotFound, s.get(fmt.Sprintf("/output/%s:2", etchings[0].TxHash()), nil))
+	assert.Equal(t, http.StatusBadRequest, s.get("/output/xyz", nil))
+
+	// the OP_RETURN output is known to the tx source but not to the index
+	var opReturn outputJSON
+	require.Equal(t, http.StatusOK, s.get(fmt.Sprintf("/output/%s:0", etchings[0].TxHash()), &opReturn))
+	assert.False(t, opReturn.Indexed)
+	assert.Empty(t, opReturn.Runes)
+	assert.NotEmpty(t, opReturn.ScriptPubkey)
+
+	var address struct {
+		Outputs       []string   `json:"outputs"`
+		SatBalance    int64      `json:"sat_balance"`
+		RunesBalances [][]string `json:"runes_balances"`
+	}
+	require.Equal(t, http.StatusOK, s.get("/address/"+s.address.EncodeAddress(), &address))
+	assert.Len(t, address.Outputs, 2)
+	assert.Equal(t, int64(2*546), address.SatBalance)
+	assert.Equal(t, [][]string{
+		{name, "1.5", "$"},
+		{runestone.Reserved(1, 2).String(), "2", "$"},
+	}, address.RunesBalances)
+	assert.Equal(t, http.StatusBadRequest, s.get("/address/bc1qxyz", nil))
+
+	// the index still answers when the tx source fails
+	delete(s.txs, etchings[0].TxHash())
+	var indexed outputJSON
+	require.Equal(t, http.StatusOK, s.get(fmt.Sprintf("/output/%s:1", etchings[0].TxHash()), &indexed))
+	assert.True(t, indexed.Indexed)
+	assert.Equal(t, output.Runes, indexed.Runes)
+	assert.Zero(t, indexed.Value)
+	assert.Nil(t, indexed.Address)
+}
+
+func TestDecode(t *testing.T) {
+	s := newTestServer(t)
+	etchings := s.etch(15)
+
+	var decoded struct {
+		Runestone map[string]runestone.Runestone `json:"runestone"`
+	}
+	require.Equal(t, http.StatusOK, s.get("/decode/"+etchings[0].TxHash().String(), &decoded))
+	if assert.Contains(t, decoded.Runestone, "Runestone") {
+		assert.Equal(t, u128P(15), decoded.Runestone["Runestone"].Etching.Premine)
+	}
+	assert.Equal(t, http.StatusNotFound, s.get("/decode/"+chainhash.Hash{}.String(), nil))
+
+	s.server = NewServer(s.index, nil)
+	assert.Equal(t, http.StatusNotImplemented, s.get("/decode/"+etchings[0].TxHash().String(), nil))
+}
+
+func u128P(n uint64) *uint128.Uint128 {
+	u := uint128.From64(n)
+	return &u
+}
+
+func strP(s string) *string {
+	return &s
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"lukechampine.com/uint128"
+)
+
+// BalanceLookup resolves the runes held by outpoints. It is implemented by
+// Index, and can be implemented on top of a remote service as well.
+type BalanceLookup interface {
+	// Balances returns the runes held by outpoint, or nil if it holds none.
+	Balances(outpoint wire.OutPoint) ([]Balance, error)
+	// RuneEntry returns the entry etched at id, or nil if there is none.
+	RuneEntry(id runestone.RuneId) (*runestone.RuneEntry, error)
+}
+
+var _ BalanceLookup = (*Index)(nil)
+
+var ErrUnknownRune = errors.New("balance of unknown rune")
+
+// UTXO is an unspent output of an address, as listed by a wallet or a block
+// explorer.
+type UTXO struct {
+	Outpoint wire.OutPoint
+	Value    int64
+}
+
+// RuneUTXO is a UTXO together with the runes it holds.
+type RuneUTXO struct {
+	UTXO
+	Balances []Balance
+}
+
+// RuneTotal is the amount of a rune held across the UTXOs of an address.
+type RuneTotal struct {
+	ID   runestone.RuneId
+	Rune runestone.SpacedRune
+	// Pile carries the divisibility and symbol to format the amount with.
+	Pile runestone.Pile
+}
+
+// String formats t like "UNCOMMON•GOODS 1.5 ⧉".
+func (t RuneTotal) String() string {
+	return t.Rune.String() + " " + t.Pile.String()
+}
+
+// AddressView is what an address owns: its rune totals, the UTXOs carrying
+// runes and the plain ones, which can be spent for fees without moving runes.
+type AddressView struct {
+	Totals   []RuneTotal
+	Runes    []RuneUTXO
+	Cardinal []UTXO
+	// Value is the total value of all UTXOs in satoshis.
+	Value int64
+}
+
+// NewAddressView joins utxos with the balances lookup has for them. Totals
+// are sorted by rune id, the UTXOs keep their order.
+func NewAddressView(lookup BalanceLookup, utxos []UTXO) (*AddressView, error) {
+	view := &AddressView{}
+	totals := make(runestone.Balances)
+	for _, utxo := range utxos {
+		view.Value += utxo.Value
+		balances, err := lookup.Balances(utxo.Outpoint)
+		if err != nil {
+			return nil, err
+		}
+		if len(balances) == 0 {
+			view.Cardinal = append(view.Cardinal, utxo)
+			continue
+		}
+		view.Runes = append(view.Runes, RuneUTXO{UTXO: utxo, Balances: balances})
+		for _, balance := range balances {
+			total := totals[balance.ID]
+			if total.Cmp(uint128.Max.Sub(balance.Amount)) > 0 {
+				return nil, runestone.ErrAmountOverflow
+			}
+			totals[balance.ID] = total.Add(balance.Amount)
+		}
+	}
+	list := make([]Balance, 0, len(totals))
+	for id, amount := range totals {
+		list = append(list, Balance{ID: id, Amount: amount})
+	}
+	sortBalances(list)
+	for _, balance := range list {
+		entry, err := lookup.RuneEntry(balance.ID)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRune, balance.ID)
+		}
+		view.Totals = append(view.Totals, RuneTotal{ID: balance.ID, Rune: entry.SpacedRune, Pile: entry.Pile(balance.Amount)})
+	}
+	return view, nil
+}
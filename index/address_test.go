@@ -0,0 +1,67 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressView(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	symbol := '$'
+	divisibility := uint8(2)
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(1050), Symbol: &symbol, Divisibility: &divisibility})
+	transfer := runestoneTx(t, &runestone.Runestone{
+		Edicts: []runestone.Edict{{ID: id, Amount: u128(1000), Output: 2}},
+	}, []*wire.TxIn{spend(etchTx, 1)}, 2)
+	c.mine(transfer)
+
+	utxos := []UTXO{
+		{Outpoint: wire.OutPoint{Hash: transfer.TxHash(), Index: 2}, Value: 1000},
+		{Outpoint: c.outpoint(), Value: 5000},
+		{Outpoint: wire.OutPoint{Hash: transfer.TxHash(), Index: 1}, Value: 1000},
+	}
+	view, err := NewAddressView(c.index, utxos)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7000), view.Value)
+	assert.Equal(t, []UTXO{utxos[1]}, view.Cardinal)
+	assert.Equal(t, []RuneUTXO{
+		{UTXO: utxos[0], Balances: []Balance{{ID: id, Amount: u128(1000)}}},
+		{UTXO: utxos[2], Balances: []Balance{{ID: id, Amount: u128(50)}}},
+	}, view.Runes)
+	if assert.Len(t, view.Totals, 1) {
+		assert.Equal(t, id, view.Totals[0].ID)
+		assert.Equal(t, u128(1050), view.Totals[0].Pile.Amount)
+		assert.Equal(t, r.String()+" 10.5\u00a0$", view.Totals[0].String())
+	}
+
+	_, err = NewAddressView(unknownRunes{c.index}, utxos)
+	assert.ErrorIs(t, err, ErrUnknownRune)
+}
+
+// unknownRunes is a lookup that has balances but no rune entries.
+type unknownRunes struct {
+	*Index
+}
+
+func (unknownRunes) RuneEntry(runestone.RuneId) (*runestone.RuneEntry, error) {
+	return nil, nil
+}
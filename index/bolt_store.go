@@ -0,0 +1,109 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"bytes"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore is a Store kept in a single bbolt database file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Get(bucket string, key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		value = bytes.Clone(b.Get(key))
+		return nil
+	})
+	return value, err
+}
+
+func (s *BoltStore) Iterate(bucket string, prefix []byte, fn func(key, value []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if err := fn(bytes.Clone(k), bytes.Clone(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) NewBatch() Batch {
+	return &boltBatch{db: s.db}
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+type boltBatch struct {
+	db     *bolt.DB
+	writes []write
+}
+
+func (b *boltBatch) Put(bucket string, key, value []byte) {
+	b.writes = append(b.writes, write{bucket: bucket, key: bytes.Clone(key), value: bytes.Clone(value)})
+}
+
+func (b *boltBatch) Delete(bucket string, key []byte) {
+	b.writes = append(b.writes, write{bucket: bucket, key: bytes.Clone(key), delete: true})
+}
+
+func (b *boltBatch) Commit() error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		for _, w := range b.writes {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(w.bucket))
+			if err != nil {
+				return err
+			}
+			if w.delete {
+				err = bucket.Delete(w.key)
+			} else {
+				err = bucket.Put(w.key, w.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.writes = nil
+	return nil
+}
@@ -0,0 +1,262 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"lukechampine.com/uint128"
+)
+
+const (
+	bucketRuneIdToEntry       = "rune_id_to_entry"
+	bucketRuneToRuneId        = "rune_to_rune_id"
+	bucketTxidToRune          = "txid_to_rune"
+	bucketOutpointToBalances  = "outpoint_to_balances"
+	bucketStatistics          = "statistics"
+	bucketHeightToBlockHash   = "height_to_block_hash"
+	bucketHeightToUndo        = "height_to_undo"
+	bucketScriptToOutpoint    = "script_hash_to_outpoint"
+	bucketOutpointToScript    = "outpoint_to_script_hash"
+	statisticHeight           = "height"
+	statisticRunes            = "runes"
+	statisticReservedRunes    = "reserved_runes"
+	balanceSize               = 8 + 4 + 16
+	outpointKeySize           = chainhash.HashSize + 4
+	runeIdKeySize             = 8 + 4
+	maxRuneEntryEncodedLength = 256
+)
+
+var errCorrupt = errors.New("corrupt index data")
+
+// Keys are big endian so that byte order matches numeric order.
+
+func runeIdKey(id runestone.RuneId) []byte {
+	key := make([]byte, runeIdKeySize)
+	binary.BigEndian.PutUint64(key, id.Block)
+	binary.BigEndian.PutUint32(key[8:], id.Tx)
+	return key
+}
+
+func decodeRuneIdKey(key []byte) (runestone.RuneId, error) {
+	if len(key) != runeIdKeySize {
+		return runestone.RuneId{}, errCorrupt
+	}
+	return runestone.RuneId{Block: binary.BigEndian.Uint64(key), Tx: binary.BigEndian.Uint32(key[8:])}, nil
+}
+
+func runeKey(r runestone.Rune) []byte {
+	return appendUint128(nil, r.Value)
+}
+
+func outpointKey(outpoint wire.OutPoint) []byte {
+	key := make([]byte, outpointKeySize)
+	copy(key, outpoint.Hash[:])
+	binary.BigEndian.PutUint32(key[chainhash.HashSize:], outpoint.Index)
+	return key
+}
+
+func decodeOutpointKey(key []byte) (wire.OutPoint, error) {
+	if len(key) != outpointKeySize {
+		return wire.OutPoint{}, errCorrupt
+	}
+	var outpoint wire.OutPoint
+	copy(outpoint.Hash[:], key)
+	outpoint.Index = binary.BigEndian.Uint32(key[chainhash.HashSize:])
+	return outpoint, nil
+}
+
+// scriptKey is the sha256 of a script, which prefixes the keys of the
+// outpoints it locks.
+func scriptKey(pkScript []byte) []byte {
+	h := sha256.Sum256(pkScript)
+	return h[:]
+}
+
+func scriptOutpointKey(script, outpoint []byte) []byte {
+	return append(append(make([]byte, 0, len(script)+len(outpoint)), script...), outpoint...)
+}
+
+func appendUint128(b []byte, n uint128.Uint128) []byte {
+	b = binary.BigEndian.AppendUint64(b, n.Hi)
+	return binary.BigEndian.AppendUint64(b, n.Lo)
+}
+
+func encodeBalances(balances []Balance) []byte {
+	b := make([]byte, 0, len(balances)*balanceSize)
+	for _, balance := range balances {
+		b = append(b, runeIdKey(balance.ID)...)
+		b = appendUint128(b, balance.Amount)
+	}
+	return b
+}
+
+func decodeBalances(b []byte) ([]Balance, error) {
+	if len(b)%balanceSize != 0 {
+		return nil, errCorrupt
+	}
+	balances := make([]Balance, 0, len(b)/balanceSize)
+	d := decoder{b: b}
+	for len(d.b) > 0 && d.err == nil {
+		balances = append(balances, Balance{
+			ID:     runestone.RuneId{Block: d.uint64(), Tx: d.uint32()},
+			Amount: d.uint128(),
+		})
+	}
+	return balances, d.err
+}
+
+func encodeRuneEntry(e *runestone.RuneEntry) []byte {
+	b := make([]byte, 0, maxRuneEntryEncodedLength)
+	b = binary.BigEndian.AppendUint64(b, e.Block)
+	b = appendUint128(b, e.Burned)
+	b = append(b, e.Divisibility)
+	b = append(b, e.Etching[:]...)
+	b = appendUint128(b, e.Mints)
+	b = binary.BigEndian.AppendUint64(b, e.Number)
+	b = appendUint128(b, e.Premine)
+	b = appendUint128(b, e.SpacedRune.Rune.Value)
+	b = binary.BigEndian.AppendUint32(b, e.SpacedRune.Spacers)
+	if e.Symbol != nil {
+		b = append(b, 1)
+		b = binary.BigEndian.AppendUint32(b, uint32(*e.Symbol))
+	} else {
+		b = append(b, 0)
+	}
+	if e.Terms != nil {
+		b = append(b, 1)
+		b = appendOptionalUint128(b, e.Terms.Amount)
+		b = appendOptionalUint128(b, e.Terms.Cap)
+		for _, h := range [...]*uint64{e.Terms.Height[0], e.Terms.Height[1], e.Terms.Offset[0], e.Terms.Offset[1]} {
+			b = appendOptionalUint64(b, h)
+		}
+	} else {
+		b = append(b, 0)
+	}
+	b = binary.BigEndian.AppendUint64(b, uint64(e.Timestamp))
+	if e.Turbo {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func decodeRuneEntry(b []byte) (*runestone.RuneEntry, error) {
+	d := decoder{b: b}
+	e := &runestone.RuneEntry{}
+	e.Block = d.uint64()
+	e.Burned = d.uint128()
+	e.Divisibility = d.byte()
+	copy(e.Etching[:], d.take(chainhash.HashSize))
+	e.Mints = d.uint128()
+	e.Number = d.uint64()
+	e.Premine = d.uint128()
+	e.SpacedRune.Rune.Value = d.uint128()
+	e.SpacedRune.Spacers = d.uint32()
+	if d.byte() == 1 {
+		symbol := rune(d.uint32())
+		e.Symbol = &symbol
+	}
+	if d.byte() == 1 {
+		e.Terms = &runestone.Terms{
+			Amount: d.optionalUint128(),
+			Cap:    d.optionalUint128(),
+		}
+		e.Terms.Height[0] = d.optionalUint64()
+		e.Terms.Height[1] = d.optionalUint64()
+		e.Terms.Offset[0] = d.optionalUint64()
+		e.Terms.Offset[1] = d.optionalUint64()
+	}
+	e.Timestamp = int64(d.uint64())
+	e.Turbo = d.byte() == 1
+	if d.err == nil && len(d.b) != 0 {
+		return nil, errCorrupt
+	}
+	return e, d.err
+}
+
+func appendOptionalUint128(b []byte, n *uint128.Uint128) []byte {
+	if n == nil {
+		return append(b, 0)
+	}
+	return appendUint128(append(b, 1), *n)
+}
+
+func appendOptionalUint64(b []byte, n *uint64) []byte {
+	if n == nil {
+		return append(b, 0)
+	}
+	return binary.BigEndian.AppendUint64(append(b, 1), *n)
+}
+
+// decoder reads fixed width fields, remembering the first short read.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil || len(d.b) < n {
+		d.err = errCorrupt
+		return make([]byte, n)
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	return d.take(1)[0]
+}
+
+func (d *decoder) uint32() uint32 {
+	return binary.BigEndian.Uint32(d.take(4))
+}
+
+func (d *decoder) uint64() uint64 {
+	return binary.BigEndian.Uint64(d.take(8))
+}
+
+func (d *decoder) uint128() uint128.Uint128 {
+	hi := d.uint64()
+	lo := d.uint64()
+	return uint128.New(lo, hi)
+}
+
+func (d *decoder) optionalUint128() *uint128.Uint128 {
+	if d.byte() == 0 {
+		return nil
+	}
+	n := d.uint128()
+	return &n
+}
+
+func (d *decoder) optionalUint64() *uint64 {
+	if d.byte() == 0 {
+		return nil
+	}
+	n := d.uint64()
+	return &n
+}
+
+func (d *decoder) runeId() runestone.RuneId {
+	return runestone.RuneId{Block: d.uint64(), Tx: d.uint32()}
+}
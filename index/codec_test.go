@@ -0,0 +1,80 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/bxelab/runestone"
+	"github.com/stretchr/testify/assert"
+	"lukechampine.com/uint128"
+)
+
+func TestRuneEntryRoundTrip(t *testing.T) {
+	symbol := '曾'
+	offset := uint64(10)
+	entry := &runestone.RuneEntry{
+		Block:        840000,
+		Burned:       u128(3),
+		Divisibility: 2,
+		Etching:      chainhash.Hash{1, 2, 3},
+		Mints:        u128(7),
+		Number:       12,
+		Premine:      uint128.Max,
+		SpacedRune:   runestone.SpacedRune{Rune: testRune(), Spacers: 5},
+		Symbol:       &symbol,
+		Terms:        &runestone.Terms{Amount: u128P(100), Offset: [2]*uint64{nil, &offset}},
+		Timestamp:    1713571767,
+		Turbo:        true,
+	}
+	decoded, err := decodeRuneEntry(encodeRuneEntry(entry))
+	assert.NoError(t, err)
+	assert.Equal(t, entry, decoded)
+
+	decoded, err = decodeRuneEntry(encodeRuneEntry(&runestone.RuneEntry{}))
+	assert.NoError(t, err)
+	assert.Equal(t, &runestone.RuneEntry{}, decoded)
+
+	_, err = decodeRuneEntry(encodeRuneEntry(entry)[:40])
+	assert.ErrorIs(t, err, errCorrupt)
+}
+
+func TestBalancesRoundTrip(t *testing.T) {
+	balances := []Balance{
+		{ID: runestone.RuneId{Block: 1, Tx: 0}, Amount: u128(1)},
+		{ID: runestone.RuneId{Block: 840000, Tx: 3}, Amount: uint128.Max},
+	}
+	decoded, err := decodeBalances(encodeBalances(balances))
+	assert.NoError(t, err)
+	assert.Equal(t, balances, decoded)
+
+	_, err = decodeBalances([]byte{1, 2, 3})
+	assert.ErrorIs(t, err, errCorrupt)
+}
+
+func TestUndoRoundTrip(t *testing.T) {
+	undo := []write{
+		{bucket: bucketOutpointToBalances, key: []byte{1, 2, 3}, value: []byte{4, 5}},
+		{bucket: bucketRuneIdToEntry, key: []byte{6}, delete: true},
+		{bucket: bucketStatistics, key: []byte(statisticHeight), value: []byte{}},
+	}
+	decoded, err := decodeUndo(encodeUndo(undo))
+	assert.NoError(t, err)
+	assert.Equal(t, undo, decoded)
+
+	_, err = decodeUndo(encodeUndo(undo)[:5])
+	assert.ErrorIs(t, err, errCorrupt)
+}
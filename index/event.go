@@ -0,0 +1,311 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/bxelab/runestone"
+	"lukechampine.com/uint128"
+)
+
+const bucketHeightToEvents = "height_to_events"
+
+// Event is a change to the rune ledger made by a transaction: one of Etched,
+// Minted, Transferred, Burned or CenotaphObserved, the events ord sends from
+// its rune updater.
+type Event interface {
+	// Tx returns the transaction that caused the event.
+	Tx() EventTx
+}
+
+// EventTx identifies the transaction an event comes from.
+type EventTx struct {
+	Height uint64
+	Txid   chainhash.Hash
+}
+
+func (e EventTx) Tx() EventTx {
+	return e
+}
+
+// Etched reports the creation of the rune ID.
+type Etched struct {
+	EventTx
+	ID runestone.RuneId
+}
+
+// Minted reports a successful mint of Amount of rune ID.
+type Minted struct {
+	EventTx
+	ID     runestone.RuneId
+	Amount uint128.Uint128
+}
+
+// Transferred reports that output Vout of the transaction received Amount of
+// rune ID, including minted and premined runes.
+type Transferred struct {
+	EventTx
+	Vout   uint32
+	ID     runestone.RuneId
+	Amount uint128.Uint128
+}
+
+// Burned reports that the transaction burned Amount of rune ID.
+type Burned struct {
+	EventTx
+	ID     runestone.RuneId
+	Amount uint128.Uint128
+}
+
+// CenotaphObserved reports a transaction whose runestone is a cenotaph, which
+// burns every rune of its inputs.
+type CenotaphObserved struct {
+	EventTx
+	Flaw runestone.Flaw
+}
+
+const (
+	eventEtched byte = iota
+	eventMinted
+	eventTransferred
+	eventBurned
+	eventCenotaphObserved
+)
+
+// Events returns the events recorded for the block indexed at height, in
+// transaction order.
+func (idx *Index) Events(height uint64) ([]Event, error) {
+	b, err := idx.store.Get(bucketHeightToEvents, heightKey(height))
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return decodeEvents(height, b)
+}
+
+// encodeEvents encodes the events of a block, leaving out the height all of
+// them share.
+func encodeEvents(events []Event) []byte {
+	var b []byte
+	for _, e := range events {
+		tx := e.Tx()
+		switch e := e.(type) {
+		case Etched:
+			b = append(b, eventEtched)
+			b = append(b, tx.Txid[:]...)
+			b = append(b, runeIdKey(e.ID)...)
+		case Minted:
+			b = append(b, eventMinted)
+			b = append(b, tx.Txid[:]...)
+			b = append(b, runeIdKey(e.ID)...)
+			b = appendUint128(b, e.Amount)
+		case Transferred:
+			b = append(b, eventTransferred)
+			b = append(b, tx.Txid[:]...)
+			b = binary.BigEndian.AppendUint32(b, e.Vout)
+			b = append(b, runeIdKey(e.ID)...)
+			b = appendUint128(b, e.Amount)
+		case Burned:
+			b = append(b, eventBurned)
+			b = append(b, tx.Txid[:]...)
+			b = append(b, runeIdKey(e.ID)...)
+			b = appendUint128(b, e.Amount)
+		case CenotaphObserved:
+			b = append(b, eventCenotaphObserved)
+			b = append(b, tx.Txid[:]...)
+			b = binary.BigEndian.AppendUint32(b, uint32(e.Flaw))
+		}
+	}
+	return b
+}
+
+func decodeEvents(height uint64, b []byte) ([]Event, error) {
+	var events []Event
+	d := decoder{b: b}
+	for len(d.b) > 0 && d.err == nil {
+		kind := d.byte()
+		tx := EventTx{Height: height}
+		copy(tx.Txid[:], d.take(chainhash.HashSize))
+		switch kind {
+		case eventEtched:
+			events = append(events, Etched{EventTx: tx, ID: d.runeId()})
+		case eventMinted:
+			events = append(events, Minted{EventTx: tx, ID: d.runeId(), Amount: d.uint128()})
+		case eventTransferred:
+			events = append(events, Transferred{EventTx: tx, Vout: d.uint32(), ID: d.runeId(), Amount: d.uint128()})
+		case eventBurned:
+			events = append(events, Burned{EventTx: tx, ID: d.runeId(), Amount: d.uint128()})
+		case eventCenotaphObserved:
+			events = append(events, CenotaphObserved{EventTx: tx, Flaw: runestone.Flaw(d.uint32())})
+		default:
+			return nil, errCorrupt
+		}
+	}
+	return events, d.err
+}
+
+// Subscription streams the events of indexed blocks in height order. It reads
+// them back from the store, so a subscriber that falls behind delays only
+// itself, never the indexer.
+//
+// When blocks are unwound the subscription continues at the first unwound
+// height, so the events of the blocks replacing them are delivered too. The
+// events already delivered for the unwound blocks are not retracted.
+type Subscription struct {
+	index *Index
+	feed  *feed
+	next  uint64 // guarded by feed.mu
+	ch    chan Event
+	done  chan struct{}
+	once  sync.Once
+	err   error
+}
+
+// Subscribe streams the events of every block indexed from now on through a
+// channel with room for buffer events.
+func (idx *Index) Subscribe(buffer int) *Subscription {
+	return idx.SubscribeFrom(idx.NextHeight(), buffer)
+}
+
+// SubscribeFrom is like Subscribe, but first replays the recorded events of
+// the blocks indexed from height on.
+func (idx *Index) SubscribeFrom(height uint64, buffer int) *Subscription {
+	s := &Subscription{
+		index: idx,
+		feed:  idx.feed,
+		next:  height,
+		ch:    make(chan Event, buffer),
+		done:  make(chan struct{}),
+	}
+	idx.feed.add(s)
+	go s.run()
+	return s
+}
+
+// Events returns the channel the events are delivered on. It is closed after
+// Close or when reading the events fails, see Err.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err returns the error that ended the subscription, once Events is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.feed.remove(s)
+		close(s.done)
+	})
+}
+
+func (s *Subscription) run() {
+	defer close(s.ch)
+	for {
+		s.feed.mu.Lock()
+		next, height, wake := s.next, s.feed.height, s.feed.wake
+		s.feed.mu.Unlock()
+		if next >= height {
+			select {
+			case <-wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		events, err := s.index.Events(next)
+		if err != nil {
+			s.err = err
+			s.Close()
+			return
+		}
+		s.feed.mu.Lock()
+		stale := s.next != next // unwound while reading
+		if !stale {
+			s.next = next + 1
+		}
+		s.feed.mu.Unlock()
+		if stale {
+			continue
+		}
+		for _, e := range events {
+			select {
+			case s.ch <- e:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// feed tells subscriptions about the blocks indexed and unwound.
+type feed struct {
+	mu            sync.Mutex
+	height        uint64
+	wake          chan struct{}
+	subscriptions map[*Subscription]struct{}
+}
+
+func newFeed(height uint64) *feed {
+	return &feed{
+		height:        height,
+		wake:          make(chan struct{}),
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+func (f *feed) add(s *Subscription) {
+	f.mu.Lock()
+	f.subscriptions[s] = struct{}{}
+	f.mu.Unlock()
+}
+
+func (f *feed) remove(s *Subscription) {
+	f.mu.Lock()
+	delete(f.subscriptions, s)
+	f.mu.Unlock()
+}
+
+// indexed records that the block before height was indexed and wakes the
+// subscriptions.
+func (f *feed) indexed(height uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.height = height
+	f.wakeLocked()
+}
+
+// unwound records that the blocks from height on were unwound, moving back
+// the subscriptions that are past it.
+func (f *feed) unwound(height uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.height = height
+	for s := range f.subscriptions {
+		if s.next > height {
+			s.next = height
+		}
+	}
+	f.wakeLocked()
+}
+
+func (f *feed) wakeLocked() {
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
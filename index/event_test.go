@@ -0,0 +1,154 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (c *testContext) events(height uint64) []Event {
+	events, err := c.index.Events(height)
+	require.NoError(c.t, err)
+	return events
+}
+
+// receive reads n events from s, failing the test if they do not arrive.
+func receive(t *testing.T, s *Subscription, n int) []Event {
+	events := make([]Event, 0, n)
+	for len(events) < n {
+		select {
+		case e, ok := <-s.Events():
+			require.True(t, ok, "subscription closed: %v", s.Err())
+			events = append(events, e)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for events", "got %d of %d", len(events), n)
+		}
+	}
+	return events
+}
+
+func TestEventsOfEtchMintTransferAndBurn(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{
+		Rune:    &r,
+		Premine: u128P(10),
+		Terms:   &runestone.Terms{Amount: u128P(100), Cap: u128P(1)},
+	})
+	etched := EventTx{Height: id.Block, Txid: etchTx.TxHash()}
+	assert.Equal(t, []Event{
+		Etched{EventTx: etched, ID: id},
+		Transferred{EventTx: etched, Vout: 1, ID: id, Amount: u128(10)},
+	}, c.events(id.Block))
+
+	mint := runestoneTx(t, &runestone.Runestone{
+		Mint:   &id,
+		Edicts: []runestone.Edict{{ID: id, Amount: u128(40), Output: 0}},
+	}, []*wire.TxIn{spend(etchTx, 1)}, 1)
+	failed := runestoneTx(t, &runestone.Runestone{Mint: &id}, []*wire.TxIn{spend(coinbase(0), 0)}, 1)
+	c.mine(mint, failed)
+	minted := EventTx{Height: id.Block + 1, Txid: mint.TxHash()}
+	assert.Equal(t, []Event{
+		Minted{EventTx: minted, ID: id, Amount: u128(100)},
+		Transferred{EventTx: minted, Vout: 1, ID: id, Amount: u128(70)},
+		Burned{EventTx: minted, ID: id, Amount: u128(40)},
+	}, c.events(id.Block+1))
+}
+
+func TestEventsOfCenotaph(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(spend(etchTx, 1))
+	script, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddOp(runestone.MAGIC_NUMBER).AddOp(txscript.OP_VERIFY).Script()
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	tx.AddTxOut(wire.NewTxOut(1000, p2wpkhScript))
+	c.mine(tx)
+
+	source := EventTx{Height: id.Block + 1, Txid: tx.TxHash()}
+	assert.Equal(t, []Event{
+		CenotaphObserved{EventTx: source, Flaw: runestone.Opcode},
+		Burned{EventTx: source, ID: id, Amount: u128(10)},
+	}, c.events(id.Block+1))
+}
+
+func TestEventsRoundTrip(t *testing.T) {
+	source := EventTx{Height: 7, Txid: [32]byte{1, 2, 3}}
+	id := runestone.RuneId{Block: 5, Tx: 9}
+	events := []Event{
+		Etched{EventTx: source, ID: id},
+		Minted{EventTx: source, ID: id, Amount: u128(1)},
+		Transferred{EventTx: source, Vout: 3, ID: id, Amount: u128(2)},
+		Burned{EventTx: source, ID: id, Amount: u128(3)},
+		CenotaphObserved{EventTx: source, Flaw: runestone.Varint},
+	}
+	decoded, err := decodeEvents(7, encodeEvents(events))
+	require.NoError(t, err)
+	assert.Equal(t, events, decoded)
+
+	_, err = decodeEvents(7, []byte{0xff})
+	assert.ErrorIs(t, err, errCorrupt)
+}
+
+func TestSubscriptionReplaysAndFollows(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+
+	s := c.index.SubscribeFrom(0, 1)
+	defer s.Close()
+	live := c.index.Subscribe(0)
+	defer live.Close()
+	replayed := receive(t, s, 2)
+	assert.Equal(t, Etched{EventTx: EventTx{Height: id.Block, Txid: etchTx.TxHash()}, ID: id}, replayed[0])
+
+	transfer := runestoneTx(t, &runestone.Runestone{}, []*wire.TxIn{spend(etchTx, 1)}, 1)
+	c.mine(transfer)
+	want := Transferred{EventTx: EventTx{Height: id.Block + 1, Txid: transfer.TxHash()}, Vout: 1, ID: id, Amount: u128(10)}
+	assert.Equal(t, []Event{want}, receive(t, s, 1))
+	assert.Equal(t, []Event{want}, receive(t, live, 1))
+
+	s.Close()
+	for range s.Events() {
+	}
+	assert.NoError(t, s.Err())
+}
+
+func TestSubscriptionFollowsUnwind(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+	s := c.index.Subscribe(4)
+	defer s.Close()
+
+	first := runestoneTx(t, &runestone.Runestone{}, []*wire.TxIn{spend(etchTx, 1)}, 1)
+	c.mine(first)
+	assert.Equal(t, first.TxHash(), receive(t, s, 1)[0].Tx().Txid)
+
+	require.NoError(t, c.index.Unwind(id.Block+1))
+	second := runestoneTx(t, &runestone.Runestone{}, []*wire.TxIn{spend(etchTx, 1)}, 2)
+	c.mine(second)
+	assert.Equal(t, second.TxHash(), receive(t, s, 1)[0].Tx().Txid)
+	assert.Nil(t, c.events(id.Block+2))
+}
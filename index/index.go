@@ -0,0 +1,331 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package index keeps a ledger of rune entries and outpoint balances by
+// applying the artifacts of runestone.Runestone.Decipher block by block, the
+// same state transition ord performs in its rune updater.
+package index
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"lukechampine.com/uint128"
+)
+
+// TxLookup resolves outputs spent by etching transactions. The index only sees
+// the blocks it is fed, so the script and confirmation height of a commit
+// transaction usually have to come from a node.
+type TxLookup interface {
+	// TxOut returns the output at outpoint and the height of the block that
+	// confirmed it.
+	TxOut(outpoint wire.OutPoint) (*wire.TxOut, uint64, error)
+}
+
+// Balance is the amount of a single rune held by an outpoint.
+type Balance struct {
+	ID     runestone.RuneId
+	Amount uint128.Uint128
+}
+
+var (
+	ErrUnexpectedHeight = errors.New("unexpected block height")
+	ErrNoTxLookup       = errors.New("no tx lookup to verify rune commitment")
+)
+
+// Index applies blocks in height order, starting at the first rune height of
+// its chain, and keeps the resulting state in a Store.
+type Index struct {
+	store  Store
+	chain  *runestone.Chain
+	lookup TxLookup
+	feed   *feed
+
+	height        uint64
+	runes         uint64
+	reservedRunes uint64
+}
+
+// NewIndex opens the index kept in store, initializing it for chain if the
+// store is empty.
+func NewIndex(store Store, chain *runestone.Chain, lookup TxLookup) (*Index, error) {
+	idx := &Index{
+		store:  store,
+		chain:  chain,
+		lookup: lookup,
+	}
+	height, err := store.Get(bucketStatistics, []byte(statisticHeight))
+	if err != nil {
+		return nil, err
+	}
+	if height == nil {
+		err = idx.init()
+	} else {
+		err = idx.load()
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx.feed = newFeed(idx.height)
+	return idx, nil
+}
+
+// load reads the statistics of the index back from its store.
+func (idx *Index) load() error {
+	for _, stat := range []struct {
+		key   string
+		value *uint64
+	}{
+		{statisticHeight, &idx.height},
+		{statisticRunes, &idx.runes},
+		{statisticReservedRunes, &idx.reservedRunes},
+	} {
+		b, err := idx.store.Get(bucketStatistics, []byte(stat.key))
+		if err != nil {
+			return err
+		}
+		if len(b) != 8 {
+			return errCorrupt
+		}
+		*stat.value = binary.BigEndian.Uint64(b)
+	}
+	return nil
+}
+
+func (idx *Index) init() error {
+	c := newCache(idx.store)
+	idx.height = idx.chain.FirstRuneHeight
+	if idx.chain.Params.Net == wire.MainNet {
+		idx.addGenesisRune(c)
+	}
+	idx.putStatistics(c, idx.height, idx.runes, idx.reservedRunes)
+	return c.commit()
+}
+
+// addGenesisRune inserts UNCOMMON•GOODS, which ord hardcodes on mainnet
+// instead of deriving it from an etching transaction.
+func (idx *Index) addGenesisRune(c *cache) {
+	id := runestone.RuneId{Block: 1, Tx: 0}
+	r := runestone.NewRune(uint128.From64(2055900680524219742))
+	amount := uint128.From64(1)
+	start := uint64(runestone.SUBSIDY_HALVING_INTERVAL * 4)
+	end := uint64(runestone.SUBSIDY_HALVING_INTERVAL * 5)
+	symbol := '⧉'
+	c.put(bucketRuneIdToEntry, runeIdKey(id), encodeRuneEntry(&runestone.RuneEntry{
+		Block:      id.Block,
+		SpacedRune: runestone.SpacedRune{Rune: r, Spacers: 128},
+		Symbol:     &symbol,
+		Terms: &runestone.Terms{
+			Amount: &amount,
+			Cap:    &uint128.Max,
+			Height: [2]*uint64{&start, &end},
+		},
+		Turbo: true,
+	}))
+	c.put(bucketRuneToRuneId, runeKey(r), runeIdKey(id))
+	c.put(bucketTxidToRune, make([]byte, chainhash.HashSize), runeKey(r))
+	idx.runes = 1
+}
+
+func (idx *Index) putStatistics(c *cache, height, runes, reservedRunes uint64) {
+	c.put(bucketStatistics, []byte(statisticHeight), binary.BigEndian.AppendUint64(nil, height))
+	c.put(bucketStatistics, []byte(statisticRunes), binary.BigEndian.AppendUint64(nil, runes))
+	c.put(bucketStatistics, []byte(statisticReservedRunes), binary.BigEndian.AppendUint64(nil, reservedRunes))
+}
+
+// Chain returns the chain the index follows.
+func (idx *Index) Chain() *runestone.Chain {
+	return idx.chain
+}
+
+// NextHeight returns the height of the block IndexBlock expects next. It is
+// safe to call while another goroutine is indexing.
+func (idx *Index) NextHeight() uint64 {
+	idx.feed.mu.Lock()
+	defer idx.feed.mu.Unlock()
+	return idx.feed.height
+}
+
+// IndexBlock applies every transaction of block, which must be at NextHeight
+// and build on the last indexed block. The changes are committed to the store
+// at once together with an undo log, so a failed block leaves the index as it
+// was and a committed one can later be unwound. The events of the block are
+// recorded with it and delivered to subscriptions.
+func (idx *Index) IndexBlock(height uint64, block *wire.MsgBlock) error {
+	if height != idx.height {
+		return fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedHeight, idx.height, height)
+	}
+	if height > 0 {
+		prev, err := idx.BlockHash(height - 1)
+		if err != nil {
+			return err
+		}
+		if prev != nil && !prev.IsEqual(&block.Header.PrevBlock) {
+			return fmt.Errorf("%w: block %d builds on %s, indexed %s", ErrReorg, height, block.Header.PrevBlock, prev)
+		}
+	}
+	u := &updater{
+		index:         idx,
+		cache:         newCache(idx.store),
+		height:        height,
+		blockTime:     block.Header.Timestamp.Unix(),
+		minimum:       idx.chain.MinimumAtHeight(height),
+		runes:         idx.runes,
+		reservedRunes: idx.reservedRunes,
+		burned:        make(map[runestone.RuneId]uint128.Uint128),
+	}
+	artifacts := idx.chain.DecipherBlock(block)
+	for i, tx := range block.Transactions {
+		var artifact *runestone.Artifact
+		var txid chainhash.Hash
+		if len(artifacts) > 0 && artifacts[0].Index == uint32(i) {
+			artifact, txid = artifacts[0].Artifact, artifacts[0].Txid
+			artifacts = artifacts[1:]
+		} else {
+			txid = tx.TxHash()
+		}
+		if err := u.indexRunes(uint32(i), tx, txid, artifact); err != nil {
+			return err
+		}
+	}
+	for id, amount := range u.burned {
+		entry, err := u.runeEntry(id)
+		if err != nil {
+			return err
+		}
+		entry.Burned = entry.Burned.Add(amount)
+		u.putRuneEntry(id, entry)
+	}
+	idx.putStatistics(u.cache, height+1, u.runes, u.reservedRunes)
+	if len(u.events) > 0 {
+		u.cache.put(bucketHeightToEvents, heightKey(height), encodeEvents(u.events))
+	}
+	hash := block.BlockHash()
+	u.cache.put(bucketHeightToBlockHash, heightKey(height), hash.CloneBytes())
+
+	undo, err := u.cache.undoLog()
+	if err != nil {
+		return err
+	}
+	u.cache.put(bucketHeightToUndo, heightKey(height), encodeUndo(undo))
+	if height >= ReorgDepth {
+		u.cache.delete(bucketHeightToUndo, heightKey(height-ReorgDepth))
+	}
+	if err := u.cache.commit(); err != nil {
+		return err
+	}
+	idx.height = height + 1
+	idx.runes = u.runes
+	idx.reservedRunes = u.reservedRunes
+	idx.feed.indexed(idx.height)
+	return nil
+}
+
+// RuneEntry returns the entry etched at id, or nil if there is none.
+func (idx *Index) RuneEntry(id runestone.RuneId) (*runestone.RuneEntry, error) {
+	b, err := idx.store.Get(bucketRuneIdToEntry, runeIdKey(id))
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return decodeRuneEntry(b)
+}
+
+var _ runestone.RuneLookup = (*Index)(nil)
+
+// RuneId returns the id of the rune named r, or nil if it was never etched.
+func (idx *Index) RuneId(r runestone.Rune) (*runestone.RuneId, error) {
+	b, err := idx.store.Get(bucketRuneToRuneId, runeKey(r))
+	if err != nil || b == nil {
+		return nil, err
+	}
+	id, err := decodeRuneIdKey(b)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// Balances returns the runes held by outpoint, sorted by rune id.
+func (idx *Index) Balances(outpoint wire.OutPoint) ([]Balance, error) {
+	b, err := idx.store.Get(bucketOutpointToBalances, outpointKey(outpoint))
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return decodeBalances(b)
+}
+
+// Outpoints returns the outpoints holding runes that are locked by pkScript,
+// in key order.
+func (idx *Index) Outpoints(pkScript []byte) ([]wire.OutPoint, error) {
+	var outpoints []wire.OutPoint
+	prefix := scriptKey(pkScript)
+	err := idx.store.Iterate(bucketScriptToOutpoint, prefix, func(key, _ []byte) error {
+		outpoint, err := decodeOutpointKey(key[len(prefix):])
+		if err != nil {
+			return err
+		}
+		outpoints = append(outpoints, outpoint)
+		return nil
+	})
+	return outpoints, err
+}
+
+// Runes returns the number of runes etched so far.
+func (idx *Index) Runes() uint64 {
+	return idx.runes
+}
+
+// ForEachRuneEntry calls fn for every etched rune in id order.
+func (idx *Index) ForEachRuneEntry(fn func(id runestone.RuneId, entry *runestone.RuneEntry) error) error {
+	return idx.store.Iterate(bucketRuneIdToEntry, nil, func(key, value []byte) error {
+		id, err := decodeRuneIdKey(key)
+		if err != nil {
+			return err
+		}
+		entry, err := decodeRuneEntry(value)
+		if err != nil {
+			return err
+		}
+		return fn(id, entry)
+	})
+}
+
+// ForEachBalance calls fn for every outpoint holding runes.
+func (idx *Index) ForEachBalance(fn func(outpoint wire.OutPoint, balances []Balance) error) error {
+	return idx.store.Iterate(bucketOutpointToBalances, nil, func(key, value []byte) error {
+		outpoint, err := decodeOutpointKey(key)
+		if err != nil {
+			return err
+		}
+		balances, err := decodeBalances(value)
+		if err != nil {
+			return err
+		}
+		return fn(outpoint, balances)
+	})
+}
+
+func sortBalances(balances []Balance) {
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].ID.Block != balances[j].ID.Block {
+			return balances[i].ID.Block < balances[j].ID.Block
+		}
+		return balances[i].ID.Tx < balances[j].ID.Tx
+	})
+}
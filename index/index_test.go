@@ -0,0 +1,377 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+var taprootScript = append([]byte{txscript.OP_1, txscript.OP_DATA_32}, make([]byte, 32)...)
+var p2wpkhScript = append([]byte{txscript.OP_0, txscript.OP_DATA_20}, make([]byte, 20)...)
+
+type mockLookup map[wire.OutPoint]struct {
+	out    *wire.TxOut
+	height uint64
+}
+
+func (m mockLookup) TxOut(outpoint wire.OutPoint) (*wire.TxOut, uint64, error) {
+	prev, ok := m[outpoint]
+	if !ok {
+		return nil, 0, errors.New("unknown outpoint")
+	}
+	return prev.out, prev.height, nil
+}
+
+// testContext feeds blocks to a regtest index, which has no rune height delay.
+type testContext struct {
+	t      *testing.T
+	index  *Index
+	lookup mockLookup
+	nonce  uint32
+}
+
+func newContext(t *testing.T) *testContext {
+	lookup := mockLookup{}
+	idx, err := NewIndex(NewMemoryStore(), runestone.Regtest, lookup)
+	require.NoError(t, err)
+	return &testContext{t: t, index: idx, lookup: lookup}
+}
+
+func (c *testContext) runeEntry(id runestone.RuneId) *runestone.RuneEntry {
+	entry, err := c.index.RuneEntry(id)
+	require.NoError(c.t, err)
+	return entry
+}
+
+func (c *testContext) runeId(r runestone.Rune) *runestone.RuneId {
+	id, err := c.index.RuneId(r)
+	require.NoError(c.t, err)
+	return id
+}
+
+func (c *testContext) balances(tx *wire.MsgTx, vout uint32) []Balance {
+	balances, err := c.index.Balances(wire.OutPoint{Hash: tx.TxHash(), Index: vout})
+	require.NoError(c.t, err)
+	return balances
+}
+
+// outpoint returns a fresh outpoint that is not known to the index.
+func (c *testContext) outpoint() wire.OutPoint {
+	c.nonce++
+	var h chainhash.Hash
+	h[0], h[1], h[2], h[3] = byte(c.nonce), byte(c.nonce>>8), byte(c.nonce>>16), 0xff
+	return wire.OutPoint{Hash: h}
+}
+
+func (c *testContext) mine(txs ...*wire.MsgTx) *wire.MsgBlock {
+	height := c.index.NextHeight()
+	block := c.block(height, txs...)
+	require.NoError(c.t, c.index.IndexBlock(height, block))
+	return block
+}
+
+// block builds a block at height on top of the last indexed block.
+func (c *testContext) block(height uint64, txs ...*wire.MsgTx) *wire.MsgBlock {
+	block := &wire.MsgBlock{
+		Header:       wire.BlockHeader{Timestamp: time.Unix(int64(height), 0)},
+		Transactions: append([]*wire.MsgTx{coinbase(height)}, txs...),
+	}
+	if height > 0 {
+		prev, err := c.index.BlockHash(height - 1)
+		require.NoError(c.t, err)
+		if prev != nil {
+			block.Header.PrevBlock = *prev
+		}
+	}
+	return block
+}
+
+// commit registers a taproot commit output for r and returns the input that
+// reveals it, confirmed early enough to etch in the next block.
+func (c *testContext) commit(r runestone.Rune) *wire.TxIn {
+	for c.index.NextHeight()+1 < runestone.COMMIT_CONFIRMATIONS {
+		c.mine()
+	}
+	outpoint := c.outpoint()
+	height := c.index.NextHeight() + 1 - runestone.COMMIT_CONFIRMATIONS
+	c.lookup[outpoint] = struct {
+		out    *wire.TxOut
+		height uint64
+	}{wire.NewTxOut(10000, taprootScript), height}
+	tapscript, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_FALSE).AddOp(txscript.OP_IF).
+		AddData(r.Commitment()).AddOp(txscript.OP_ENDIF).Script()
+	return wire.NewTxIn(&outpoint, nil, wire.TxWitness{make([]byte, 64), tapscript, make([]byte, 33)})
+}
+
+func coinbase(height uint64) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex}, []byte{byte(height), byte(height >> 8)}, nil))
+	tx.AddTxOut(wire.NewTxOut(50, p2wpkhScript))
+	return tx
+}
+
+func runestoneTx(t *testing.T, rs *runestone.Runestone, ins []*wire.TxIn, outputs int) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for _, in := range ins {
+		tx.AddTxIn(in)
+	}
+	script, err := rs.Encipher()
+	assert.NoError(t, err)
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	for i := 0; i < outputs; i++ {
+		tx.AddTxOut(wire.NewTxOut(1000, p2wpkhScript))
+	}
+	return tx
+}
+
+func spend(tx *wire.MsgTx, vout uint32) *wire.TxIn {
+	return wire.NewTxIn(&wire.OutPoint{Hash: tx.TxHash(), Index: vout}, nil, nil)
+}
+
+func testRune() runestone.Rune {
+	return runestone.NewRune(runestone.STEPS[13].Add64(1))
+}
+
+func u128(n uint64) uint128.Uint128 {
+	return uint128.From64(n)
+}
+
+func u128P(n uint64) *uint128.Uint128 {
+	u := uint128.From64(n)
+	return &u
+}
+
+func (c *testContext) etch(etching *runestone.Etching) (*wire.MsgTx, runestone.RuneId) {
+	in := wire.NewTxIn(&wire.OutPoint{}, nil, nil)
+	if etching.Rune != nil {
+		in = c.commit(*etching.Rune)
+	} else {
+		outpoint := c.outpoint()
+		in.PreviousOutPoint = outpoint
+	}
+	tx := runestoneTx(c.t, &runestone.Runestone{Etching: etching}, []*wire.TxIn{in}, 1)
+	id := runestone.RuneId{Block: c.index.NextHeight(), Tx: 1}
+	c.mine(tx)
+	return tx, id
+}
+
+func TestEtchingWithPremine(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	symbol := '$'
+	tx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(1000), Symbol: &symbol})
+
+	entry := c.runeEntry(id)
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, r, entry.SpacedRune.Rune)
+		assert.Equal(t, u128(1000), entry.Premine)
+		assert.Equal(t, tx.TxHash(), entry.Etching)
+		assert.Equal(t, &symbol, entry.Symbol)
+	}
+	assert.Equal(t, &id, c.runeId(r))
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(1000)}}, c.balances(tx, 1))
+}
+
+func TestEtchingWithoutCommitmentIsIgnored(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	in := wire.NewTxIn(&wire.OutPoint{Index: 7}, nil, nil)
+	tx := runestoneTx(t, &runestone.Runestone{Etching: &runestone.Etching{Rune: &r, Premine: u128P(1000)}}, []*wire.TxIn{in}, 1)
+	c.mine(tx)
+
+	assert.Nil(t, c.runeId(r))
+	assert.Nil(t, c.balances(tx, 1))
+}
+
+func TestEtchingWithImmatureCommitmentIsIgnored(t *testing.T) {
+	c := newContext(t)
+	for i := 0; i < 10; i++ {
+		c.mine()
+	}
+	r := testRune()
+	in := c.commit(r)
+	prev := c.lookup[in.PreviousOutPoint]
+	prev.height++
+	c.lookup[in.PreviousOutPoint] = prev
+	tx := runestoneTx(t, &runestone.Runestone{Etching: &runestone.Etching{Rune: &r}}, []*wire.TxIn{in}, 1)
+	c.mine(tx)
+
+	assert.Nil(t, c.runeId(r))
+}
+
+func TestUnnamedEtchingGetsReservedRune(t *testing.T) {
+	c := newContext(t)
+	c.mine()
+	_, id := c.etch(&runestone.Etching{Premine: u128P(1)})
+
+	entry := c.runeEntry(id)
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, runestone.Reserved(id.Block, id.Tx), entry.SpacedRune.Rune)
+	}
+}
+
+func TestMintAndTransfer(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	_, id := c.etch(&runestone.Etching{
+		Rune:  &r,
+		Terms: &runestone.Terms{Amount: u128P(100), Cap: u128P(2)},
+	})
+
+	mints := make([]*wire.MsgTx, 3)
+	for i := range mints {
+		mints[i] = runestoneTx(t, &runestone.Runestone{Mint: &id}, []*wire.TxIn{spend(coinbase(uint64(i)), 0)}, 1)
+	}
+	c.mine(mints...)
+
+	assert.Equal(t, u128(2), c.runeEntry(id).Mints)
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(100)}}, c.balances(mints[1], 1))
+	assert.Nil(t, c.balances(mints[2], 1))
+
+	transfer := runestoneTx(t, &runestone.Runestone{
+		Edicts: []runestone.Edict{{ID: id, Amount: u128(30), Output: 2}},
+	}, []*wire.TxIn{spend(mints[0], 1), spend(mints[1], 1)}, 2)
+	c.mine(transfer)
+
+	assert.Nil(t, c.balances(mints[0], 1))
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(170)}}, c.balances(transfer, 1))
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(30)}}, c.balances(transfer, 2))
+}
+
+func TestEdictSplitsAcrossOutputs(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+
+	tx := runestoneTx(t, &runestone.Runestone{
+		Edicts: []runestone.Edict{{ID: id, Amount: uint128.Zero, Output: 4}},
+	}, []*wire.TxIn{spend(etchTx, 1)}, 3)
+	c.mine(tx)
+
+	for vout, amount := range []uint64{4, 3, 3} {
+		assert.Equal(t, []Balance{{ID: id, Amount: u128(amount)}}, c.balances(tx, uint32(vout+1)))
+	}
+}
+
+func TestCenotaphBurnsInputs(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(spend(etchTx, 1))
+	script, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddOp(runestone.MAGIC_NUMBER).AddOp(txscript.OP_VERIFY).Script()
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	tx.AddTxOut(wire.NewTxOut(1000, p2wpkhScript))
+	c.mine(tx)
+
+	assert.Nil(t, c.balances(tx, 1))
+	assert.Equal(t, u128(10), c.runeEntry(id).Burned)
+}
+
+func TestPointerAndOpReturnBurn(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+
+	pointer := uint32(2)
+	tx := runestoneTx(t, &runestone.Runestone{
+		Edicts:  []runestone.Edict{{ID: id, Amount: u128(4), Output: 0}},
+		Pointer: &pointer,
+	}, []*wire.TxIn{spend(etchTx, 1)}, 2)
+	c.mine(tx)
+
+	assert.Nil(t, c.balances(tx, 1))
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(6)}}, c.balances(tx, 2))
+	assert.Equal(t, u128(4), c.runeEntry(id).Burned)
+}
+
+func TestIndexBlockRejectsUnexpectedHeight(t *testing.T) {
+	c := newContext(t)
+	err := c.index.IndexBlock(1, &wire.MsgBlock{})
+	assert.ErrorIs(t, err, ErrUnexpectedHeight)
+}
+
+func TestMainnetGenesisRune(t *testing.T) {
+	idx, err := NewIndex(NewMemoryStore(), runestone.Mainnet, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(840000), idx.NextHeight())
+	id, err := idx.RuneId(runestone.NewRune(uint128.From64(2055900680524219742)))
+	require.NoError(t, err)
+	if assert.NotNil(t, id) {
+		entry, err := idx.RuneEntry(*id)
+		require.NoError(t, err)
+		assert.Equal(t, "UNCOMMON•GOODS", entry.SpacedRune.String())
+	}
+}
+
+func TestIndexSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	store, err := OpenBoltStore(path)
+	require.NoError(t, err)
+	lookup := mockLookup{}
+	idx, err := NewIndex(store, runestone.Regtest, lookup)
+	require.NoError(t, err)
+	c := &testContext{t: t, index: idx, lookup: lookup}
+	r := testRune()
+	tx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(5)})
+	require.NoError(t, store.Close())
+
+	store, err = OpenBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	c.index, err = NewIndex(store, runestone.Regtest, lookup)
+	require.NoError(t, err)
+	assert.Equal(t, id.Block+1, c.index.NextHeight())
+	assert.Equal(t, uint64(1), c.index.Runes())
+	assert.Equal(t, &id, c.runeId(r))
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(5)}}, c.balances(tx, 1))
+}
+
+func TestOutpointsByScript(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+	outpoints := func() []wire.OutPoint {
+		outpoints, err := c.index.Outpoints(p2wpkhScript)
+		require.NoError(t, err)
+		return outpoints
+	}
+	assert.Equal(t, []wire.OutPoint{{Hash: etchTx.TxHash(), Index: 1}}, outpoints())
+
+	transfer := runestoneTx(t, &runestone.Runestone{
+		Edicts: []runestone.Edict{{ID: id, Amount: u128(4), Output: 2}},
+	}, []*wire.TxIn{spend(etchTx, 1)}, 2)
+	c.mine(transfer)
+	assert.Equal(t, []wire.OutPoint{{Hash: transfer.TxHash(), Index: 1}, {Hash: transfer.TxHash(), Index: 2}}, outpoints())
+
+	require.NoError(t, c.index.Unwind(id.Block+1))
+	assert.Equal(t, []wire.OutPoint{{Hash: etchTx.TxHash(), Index: 1}}, outpoints())
+	none, err := c.index.Outpoints(taprootScript)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
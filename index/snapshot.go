@@ -0,0 +1,400 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/bxelab/runestone"
+)
+
+// A snapshot holds the ledger of an index at a height, so that a new index
+// can start from there instead of from the first rune height. It is laid out
+// as
+//
+//	magic "RUNESNAP", version uint32, network uint32
+//	next height uint64, runes uint64, reserved runes uint64
+//	block hash flag byte, and if 1 the hash of the last indexed block
+//	records: bucket byte (1-based), key and value each prefixed by a uvarint length
+//	0 byte
+//	sha256 of everything above
+//
+// with integers in big endian. Undo logs and events are left out, so an
+// imported index cannot unwind below the snapshot height and its events start
+// there.
+const (
+	SnapshotVersion = 1
+
+	snapshotMagic          = "RUNESNAP"
+	maxSnapshotKeyLength   = 128
+	maxSnapshotValueLength = 1 << 24
+)
+
+// snapshotBatchSize is the number of records ImportSnapshot commits at once.
+var snapshotBatchSize = 10000
+
+// snapshotBuckets are the buckets a snapshot carries, numbered from 1 in this
+// order. The order must not change within a version.
+var snapshotBuckets = []string{
+	bucketRuneIdToEntry,
+	bucketRuneToRuneId,
+	bucketTxidToRune,
+	bucketOutpointToBalances,
+	bucketOutpointToScript,
+	bucketScriptToOutpoint,
+}
+
+var (
+	ErrSnapshotFormat   = errors.New("not a rune index snapshot")
+	ErrSnapshotVersion  = errors.New("unsupported snapshot version")
+	ErrSnapshotChain    = errors.New("snapshot is for another chain")
+	ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+	ErrSnapshotCorrupt  = errors.New("corrupt snapshot")
+	ErrStoreNotEmpty    = errors.New("store already holds an index")
+)
+
+// ExportSnapshot writes the ledger of idx to w. It must not run while blocks
+// are being indexed or unwound.
+func (idx *Index) ExportSnapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	h := sha256.New()
+	out := io.MultiWriter(bw, h)
+
+	header := []byte(snapshotMagic)
+	header = binary.BigEndian.AppendUint32(header, SnapshotVersion)
+	header = binary.BigEndian.AppendUint32(header, uint32(idx.chain.Params.Net))
+	header = binary.BigEndian.AppendUint64(header, idx.height)
+	header = binary.BigEndian.AppendUint64(header, idx.runes)
+	header = binary.BigEndian.AppendUint64(header, idx.reservedRunes)
+	var last *chainhash.Hash
+	if idx.height > 0 {
+		var err error
+		last, err = idx.BlockHash(idx.height - 1)
+		if err != nil {
+			return err
+		}
+	}
+	if last != nil {
+		header = append(header, 1)
+		header = append(header, last[:]...)
+	} else {
+		header = append(header, 0)
+	}
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	var record []byte
+	for i, bucket := range snapshotBuckets {
+		err := idx.store.Iterate(bucket, nil, func(key, value []byte) error {
+			record = append(record[:0], byte(i+1))
+			record = binary.AppendUvarint(record, uint64(len(key)))
+			record = append(record, key...)
+			record = binary.AppendUvarint(record, uint64(len(value)))
+			record = append(record, value...)
+			_, err := out.Write(record)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := out.Write([]byte{0}); err != nil {
+		return err
+	}
+	if _, err := bw.Write(h.Sum(nil)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// SnapshotInfo is the header of a snapshot.
+type SnapshotInfo struct {
+	Version uint32
+	// Height is the height of the last block in the snapshot, and BlockHash
+	// its hash, which should be checked against a trusted node. If no block
+	// was indexed yet, BlockHash is nil and Height is the first rune height.
+	Height    uint64
+	BlockHash *chainhash.Hash
+	Runes     uint64
+}
+
+// ImportSnapshot reads a snapshot of chain written by ExportSnapshot into
+// store, which must be empty, and opens the index it holds. The snapshot is
+// read twice: first to check that it decodes and matches its checksum, then to
+// write it in batches of snapshotBatchSize records. The statistics go last, so
+// a store left by a failed import holds no index, and the records such an
+// import wrote are cleared before the next one writes its own.
+func ImportSnapshot(r io.ReadSeeker, store Store, chain *runestone.Chain, lookup TxLookup) (*Index, *SnapshotInfo, error) {
+	existing, err := store.Get(bucketStatistics, []byte(statisticHeight))
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != nil {
+		return nil, nil, ErrStoreNotEmpty
+	}
+
+	if _, err := readSnapshot(r, chain, nil); err != nil {
+		return nil, nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	if err := clearSnapshotBuckets(store); err != nil {
+		return nil, nil, err
+	}
+	batch, pending := store.NewBatch(), 0
+	header, err := readSnapshot(r, chain, func(bucket string, key, value []byte) error {
+		batch.Put(bucket, key, value)
+		if pending++; pending < snapshotBatchSize {
+			return nil
+		}
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+		batch, pending = store.NewBatch(), 0
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := batch.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	batch = store.NewBatch()
+	if header.info.BlockHash != nil {
+		batch.Put(bucketHeightToBlockHash, heightKey(header.info.Height), header.info.BlockHash.CloneBytes())
+	}
+	batch.Put(bucketStatistics, []byte(statisticRunes), binary.BigEndian.AppendUint64(nil, header.info.Runes))
+	batch.Put(bucketStatistics, []byte(statisticReservedRunes), binary.BigEndian.AppendUint64(nil, header.reservedRunes))
+	batch.Put(bucketStatistics, []byte(statisticHeight), binary.BigEndian.AppendUint64(nil, header.height))
+	if err := batch.Commit(); err != nil {
+		return nil, nil, err
+	}
+	idx, err := NewIndex(store, chain, lookup)
+	if err != nil {
+		return nil, nil, err
+	}
+	return idx, &header.info, nil
+}
+
+// errBatchFull stops collecting keys once a batch is full.
+var errBatchFull = errors.New("batch full")
+
+// clearSnapshotBuckets deletes the records left in the snapshot buckets by an
+// import that failed, in batches of snapshotBatchSize keys.
+func clearSnapshotBuckets(store Store) error {
+	for _, bucket := range snapshotBuckets {
+		for {
+			var keys [][]byte
+			err := store.Iterate(bucket, nil, func(key, _ []byte) error {
+				keys = append(keys, bytes.Clone(key))
+				if len(keys) == snapshotBatchSize {
+					return errBatchFull
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errBatchFull) {
+				return err
+			}
+			if len(keys) == 0 {
+				break
+			}
+			batch := store.NewBatch()
+			for _, key := range keys {
+				batch.Delete(bucket, key)
+			}
+			if err := batch.Commit(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotHeader is the decoded header of a snapshot.
+type snapshotHeader struct {
+	info          SnapshotInfo
+	height        uint64 // next height of the index
+	reservedRunes uint64
+}
+
+// readSnapshot decodes a snapshot of chain, passing its records to fn if it
+// is not nil. It fails once the end is reached if the checksum does not match,
+// so records passed to fn are not verified yet.
+func readSnapshot(r io.Reader, chain *runestone.Chain, fn func(bucket string, key, value []byte) error) (*snapshotHeader, error) {
+	in := &hashReader{r: bufio.NewReader(r), h: sha256.New()}
+	buf := make([]byte, len(snapshotMagic)+4+4+8+8+8+1)
+	if _, err := io.ReadFull(in, buf); err != nil {
+		return nil, snapshotReadError(err)
+	}
+	if string(buf[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, ErrSnapshotFormat
+	}
+	d := decoder{b: buf[len(snapshotMagic):]}
+	header := &snapshotHeader{info: SnapshotInfo{Version: d.uint32()}}
+	if header.info.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, header.info.Version)
+	}
+	if net := d.uint32(); net != uint32(chain.Params.Net) {
+		return nil, fmt.Errorf("%w: network %#x, expected %s", ErrSnapshotChain, net, chain)
+	}
+	header.height, header.info.Runes, header.reservedRunes = d.uint64(), d.uint64(), d.uint64()
+	header.info.Height = header.height
+	if d.byte() == 1 {
+		header.info.BlockHash = new(chainhash.Hash)
+		if _, err := io.ReadFull(in, header.info.BlockHash[:]); err != nil {
+			return nil, snapshotReadError(err)
+		}
+		if header.height == 0 {
+			return nil, ErrSnapshotCorrupt
+		}
+		header.info.Height = header.height - 1
+	}
+
+	entries := uint64(0)
+	for {
+		tag, err := in.ReadByte()
+		if err != nil {
+			return nil, snapshotReadError(err)
+		}
+		if tag == 0 {
+			break
+		}
+		if int(tag) > len(snapshotBuckets) {
+			return nil, fmt.Errorf("%w: unknown bucket %d", ErrSnapshotCorrupt, tag)
+		}
+		bucket := snapshotBuckets[tag-1]
+		key, err := in.readField(maxSnapshotKeyLength)
+		if err != nil {
+			return nil, err
+		}
+		value, err := in.readField(maxSnapshotValueLength)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSnapshotRecord(bucket, key, value); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrSnapshotCorrupt, bucket, err)
+		}
+		if bucket == bucketRuneIdToEntry {
+			entries++
+		}
+		if fn != nil {
+			if err := fn(bucket, key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	checksum := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(in.r, checksum); err != nil {
+		return nil, snapshotReadError(err)
+	}
+	if !bytes.Equal(checksum, in.h.Sum(nil)) {
+		return nil, ErrSnapshotChecksum
+	}
+	if entries != header.info.Runes {
+		return nil, fmt.Errorf("%w: %d rune entries, expected %d", ErrSnapshotCorrupt, entries, header.info.Runes)
+	}
+	return header, nil
+}
+
+// checkSnapshotRecord checks that a record decodes like the index would.
+func checkSnapshotRecord(bucket string, key, value []byte) error {
+	switch bucket {
+	case bucketRuneIdToEntry:
+		if _, err := decodeRuneIdKey(key); err != nil {
+			return err
+		}
+		_, err := decodeRuneEntry(value)
+		return err
+	case bucketRuneToRuneId:
+		if len(key) != 16 {
+			return errCorrupt
+		}
+		_, err := decodeRuneIdKey(value)
+		return err
+	case bucketTxidToRune:
+		if len(key) != chainhash.HashSize || len(value) != 16 {
+			return errCorrupt
+		}
+	case bucketOutpointToBalances:
+		if _, err := decodeOutpointKey(key); err != nil {
+			return err
+		}
+		_, err := decodeBalances(value)
+		return err
+	case bucketOutpointToScript:
+		if len(key) != outpointKeySize || len(value) != sha256.Size {
+			return errCorrupt
+		}
+	case bucketScriptToOutpoint:
+		if len(key) != sha256.Size+outpointKeySize {
+			return errCorrupt
+		}
+	}
+	return nil
+}
+
+// hashReader hashes everything read through it.
+type hashReader struct {
+	r *bufio.Reader
+	h hash.Hash
+}
+
+func (r *hashReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.h.Write(p[:n])
+	return n, err
+}
+
+func (r *hashReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.h.Write([]byte{b})
+	}
+	return b, err
+}
+
+// readField reads a uvarint length and that many bytes.
+func (r *hashReader) readField(max uint64) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, snapshotReadError(err)
+	}
+	if n > max {
+		return nil, fmt.Errorf("%w: field of %d bytes", ErrSnapshotCorrupt, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, snapshotReadError(err)
+	}
+	return b, nil
+}
+
+func snapshotReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated", ErrSnapshotCorrupt)
+	}
+	return err
+}
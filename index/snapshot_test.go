@@ -0,0 +1,201 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (c *testContext) snapshot() []byte {
+	var buf bytes.Buffer
+	require.NoError(c.t, c.index.ExportSnapshot(&buf))
+	return buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+	_, unnamed := c.etch(&runestone.Etching{Premine: u128P(3)})
+	tip := c.mine()
+	snapshot := c.snapshot()
+
+	store := NewMemoryStore()
+	idx, info, err := ImportSnapshot(bytes.NewReader(snapshot), store, runestone.Regtest, c.lookup)
+	require.NoError(t, err)
+	tipHash := tip.BlockHash()
+	assert.Equal(t, &SnapshotInfo{
+		Version:   SnapshotVersion,
+		Height:    c.index.NextHeight() - 1,
+		BlockHash: &tipHash,
+		Runes:     2,
+	}, info)
+
+	imported := &testContext{t: t, index: idx, lookup: c.lookup, nonce: c.nonce}
+	assert.Equal(t, c.index.NextHeight(), idx.NextHeight())
+	assert.Equal(t, c.index.Runes(), idx.Runes())
+	assert.Equal(t, c.runeEntry(id), imported.runeEntry(id))
+	assert.Equal(t, c.runeEntry(unnamed), imported.runeEntry(unnamed))
+	assert.Equal(t, &id, imported.runeId(r))
+	assert.Equal(t, c.balances(etchTx, 1), imported.balances(etchTx, 1))
+	outpoints, err := idx.Outpoints(p2wpkhScript)
+	require.NoError(t, err)
+	assert.Contains(t, outpoints, wire.OutPoint{Hash: etchTx.TxHash(), Index: 1})
+	assert.Equal(t, snapshot, imported.snapshot())
+
+	// The imported index continues from the snapshot height.
+	transfer := runestoneTx(t, &runestone.Runestone{
+		Edicts: []runestone.Edict{{ID: id, Amount: u128(4), Output: 2}},
+	}, []*wire.TxIn{spend(etchTx, 1)}, 2)
+	imported.mine(transfer)
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(4)}}, imported.balances(transfer, 2))
+	assert.Empty(t, imported.balances(etchTx, 1))
+}
+
+func TestSnapshotOfEmptyIndex(t *testing.T) {
+	c := newContext(t)
+	idx, info, err := ImportSnapshot(bytes.NewReader(c.snapshot()), NewMemoryStore(), runestone.Regtest, c.lookup)
+	require.NoError(t, err)
+	assert.Nil(t, info.BlockHash)
+	assert.Equal(t, c.index.NextHeight(), idx.NextHeight())
+}
+
+func TestSnapshotRejectsInvalidInput(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+	snapshot := c.snapshot()
+	modified := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), snapshot...))
+	}
+
+	tests := []struct {
+		name     string
+		snapshot []byte
+		chain    *runestone.Chain
+		err      error
+	}{
+		{"magic", modified(func(b []byte) []byte { b[0] = 'X'; return b }), runestone.Regtest, ErrSnapshotFormat},
+		{"version", modified(func(b []byte) []byte { b[11] = 2; return b }), runestone.Regtest, ErrSnapshotVersion},
+		{"chain", snapshot, runestone.Mainnet, ErrSnapshotChain},
+		// The hash of the last block sits right after the fixed size header.
+		{"checksum", modified(func(b []byte) []byte { b[41] ^= 1; return b }), runestone.Regtest, ErrSnapshotChecksum},
+		{"truncated", snapshot[:len(snapshot)-40], runestone.Regtest, ErrSnapshotCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			_, _, err := ImportSnapshot(bytes.NewReader(tt.snapshot), store, tt.chain, c.lookup)
+			assert.ErrorIs(t, err, tt.err)
+			height, err := store.Get(bucketStatistics, []byte(statisticHeight))
+			require.NoError(t, err)
+			assert.Nil(t, height, "nothing is written on failure")
+		})
+	}
+}
+
+func TestSnapshotImportNeedsEmptyStore(t *testing.T) {
+	c := newContext(t)
+	_, _, err := ImportSnapshot(bytes.NewReader(c.snapshot()), c.index.store, runestone.Regtest, c.lookup)
+	assert.ErrorIs(t, err, ErrStoreNotEmpty)
+}
+
+// failingStore fails the commit of its nth batch.
+type failingStore struct {
+	Store
+	n int
+}
+
+func (s *failingStore) NewBatch() Batch {
+	s.n--
+	return failingBatch{Batch: s.Store.NewBatch(), fail: s.n == 0}
+}
+
+type failingBatch struct {
+	Batch
+	fail bool
+}
+
+func (b failingBatch) Commit() error {
+	if b.fail {
+		return errors.New("disk full")
+	}
+	return b.Batch.Commit()
+}
+
+func TestSnapshotImportsInBatches(t *testing.T) {
+	defer func(size int) { snapshotBatchSize = size }(snapshotBatchSize)
+	snapshotBatchSize = 2
+
+	c := newContext(t)
+	r := testRune()
+	etchTx, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+	c.etch(&runestone.Etching{Premine: u128P(3)})
+	snapshot := c.snapshot()
+
+	// An import failing halfway leaves no index behind, so it can be retried.
+	store := &failingStore{Store: NewMemoryStore(), n: 2}
+	_, _, err := ImportSnapshot(bytes.NewReader(snapshot), store, runestone.Regtest, c.lookup)
+	assert.ErrorContains(t, err, "disk full")
+	height, err := store.Get(bucketStatistics, []byte(statisticHeight))
+	require.NoError(t, err)
+	assert.Nil(t, height)
+
+	idx, _, err := ImportSnapshot(bytes.NewReader(snapshot), store.Store, runestone.Regtest, c.lookup)
+	require.NoError(t, err)
+	imported := &testContext{t: t, index: idx}
+	assert.Equal(t, &id, imported.runeId(r))
+	assert.Equal(t, c.balances(etchTx, 1), imported.balances(etchTx, 1))
+	assert.Equal(t, snapshot, imported.snapshot())
+}
+
+func TestSnapshotImportClearsFailedImport(t *testing.T) {
+	defer func(size int) { snapshotBatchSize = size }(snapshotBatchSize)
+	snapshotBatchSize = 2
+
+	a := newContext(t)
+	r := testRune()
+	a.etch(&runestone.Etching{Rune: &r, Premine: u128P(10)})
+	a.etch(&runestone.Etching{Premine: u128P(3)})
+
+	store := &failingStore{Store: NewMemoryStore(), n: 3}
+	_, _, err := ImportSnapshot(bytes.NewReader(a.snapshot()), store, runestone.Regtest, a.lookup)
+	assert.ErrorContains(t, err, "disk full")
+	left := 0
+	for _, bucket := range snapshotBuckets {
+		require.NoError(t, store.Iterate(bucket, nil, func(_, _ []byte) error {
+			left++
+			return nil
+		}))
+	}
+	require.NotZero(t, left, "the failed import wrote some records")
+
+	// A different snapshot imported into the same store holds none of them.
+	b := newContext(t)
+	b.etch(&runestone.Etching{Premine: u128P(7)})
+	snapshot := b.snapshot()
+	idx, _, err := ImportSnapshot(bytes.NewReader(snapshot), store.Store, runestone.Regtest, b.lookup)
+	require.NoError(t, err)
+	imported := &testContext{t: t, index: idx}
+	assert.Nil(t, imported.runeId(r))
+	assert.Equal(t, snapshot, imported.snapshot())
+}
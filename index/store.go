@@ -0,0 +1,189 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// Store is the key-value storage behind an Index. Keys live in named buckets
+// and are iterated in byte order.
+type Store interface {
+	// Get returns the value of key, or nil if it is not set.
+	Get(bucket string, key []byte) ([]byte, error)
+	// Iterate calls fn for every key in bucket starting with prefix, in key
+	// order, until fn returns an error.
+	Iterate(bucket string, prefix []byte, fn func(key, value []byte) error) error
+	// NewBatch returns a batch of writes that is applied atomically on Commit.
+	NewBatch() Batch
+	Close() error
+}
+
+// Batch collects writes to a Store.
+type Batch interface {
+	Put(bucket string, key, value []byte)
+	Delete(bucket string, key []byte)
+	Commit() error
+}
+
+// MemoryStore is a Store that keeps everything in memory, mostly useful for
+// tests and short-lived tools.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+func (s *MemoryStore) Get(bucket string, key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.buckets[bucket][string(key)]
+	if !ok {
+		return nil, nil
+	}
+	return bytes.Clone(value), nil
+}
+
+func (s *MemoryStore) Iterate(bucket string, prefix []byte, fn func(key, value []byte) error) error {
+	s.mu.RLock()
+	b := s.buckets[bucket]
+	keys := make([]string, 0, len(b))
+	for key := range b {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+	values := make([][]byte, len(keys))
+	sort.Strings(keys)
+	for i, key := range keys {
+		values[i] = bytes.Clone(b[key])
+	}
+	s.mu.RUnlock()
+
+	for i, key := range keys {
+		if err := fn([]byte(key), values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) NewBatch() Batch {
+	return &memoryBatch{store: s}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+type write struct {
+	bucket string
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+type memoryBatch struct {
+	store  *MemoryStore
+	writes []write
+}
+
+func (b *memoryBatch) Put(bucket string, key, value []byte) {
+	b.writes = append(b.writes, write{bucket: bucket, key: bytes.Clone(key), value: bytes.Clone(value)})
+}
+
+func (b *memoryBatch) Delete(bucket string, key []byte) {
+	b.writes = append(b.writes, write{bucket: bucket, key: bytes.Clone(key), delete: true})
+}
+
+func (b *memoryBatch) Commit() error {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	for _, w := range b.writes {
+		bucket, ok := b.store.buckets[w.bucket]
+		if !ok {
+			bucket = make(map[string][]byte)
+			b.store.buckets[w.bucket] = bucket
+		}
+		if w.delete {
+			delete(bucket, string(w.key))
+		} else {
+			bucket[string(w.key)] = w.value
+		}
+	}
+	b.writes = nil
+	return nil
+}
+
+// cache buffers the writes of a block on top of a Store, so later
+// transactions of the block see them before they are committed together.
+type cache struct {
+	store  Store
+	writes map[string]map[string]*write
+	order  []*write
+}
+
+func newCache(store Store) *cache {
+	return &cache{store: store, writes: make(map[string]map[string]*write)}
+}
+
+func (c *cache) get(bucket string, key []byte) ([]byte, error) {
+	if w, ok := c.writes[bucket][string(key)]; ok {
+		if w.delete {
+			return nil, nil
+		}
+		return w.value, nil
+	}
+	return c.store.Get(bucket, key)
+}
+
+func (c *cache) put(bucket string, key, value []byte) {
+	c.set(&write{bucket: bucket, key: key, value: value})
+}
+
+func (c *cache) delete(bucket string, key []byte) {
+	c.set(&write{bucket: bucket, key: key, delete: true})
+}
+
+func (c *cache) set(w *write) {
+	b, ok := c.writes[w.bucket]
+	if !ok {
+		b = make(map[string]*write)
+		c.writes[w.bucket] = b
+	}
+	if prev, ok := b[string(w.key)]; ok {
+		*prev = *w
+		return
+	}
+	b[string(w.key)] = w
+	c.order = append(c.order, w)
+}
+
+func (c *cache) commit() error {
+	batch := c.store.NewBatch()
+	for _, w := range c.order {
+		if w.delete {
+			batch.Delete(w.bucket, w.key)
+		} else {
+			batch.Put(w.bucket, w.key, w.value)
+		}
+	}
+	return batch.Commit()
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store Store) {
+	value, err := store.Get("bucket", []byte("missing"))
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	batch := store.NewBatch()
+	batch.Put("bucket", []byte("b2"), []byte("two"))
+	batch.Put("bucket", []byte("b1"), []byte("one"))
+	batch.Put("bucket", []byte("a1"), []byte("other"))
+	batch.Put("other", []byte("b3"), []byte("three"))
+	value, err = store.Get("bucket", []byte("b1"))
+	assert.NoError(t, err)
+	assert.Nil(t, value, "writes must not be visible before commit")
+	require.NoError(t, batch.Commit())
+
+	value, err = store.Get("bucket", []byte("b1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("one"), value)
+
+	var keys []string
+	err = store.Iterate("bucket", []byte("b"), func(key, value []byte) error {
+		keys = append(keys, string(key)+"="+string(value))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b1=one", "b2=two"}, keys)
+
+	batch = store.NewBatch()
+	batch.Delete("bucket", []byte("b1"))
+	batch.Put("bucket", []byte("b2"), []byte("updated"))
+	require.NoError(t, batch.Commit())
+
+	value, err = store.Get("bucket", []byte("b1"))
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+	value, err = store.Get("bucket", []byte("b2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("updated"), value)
+	assert.NoError(t, store.Iterate("missing", nil, func(key, value []byte) error {
+		t.Fatal("unexpected key in missing bucket")
+		return nil
+	}))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestBoltStore(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "store.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	testStore(t, store)
+}
+
+func TestCacheSeesPendingWrites(t *testing.T) {
+	store := NewMemoryStore()
+	c := newCache(store)
+	c.put("bucket", []byte("key"), []byte("value"))
+	value, err := c.get("bucket", []byte("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	c.delete("bucket", []byte("key"))
+	value, err = c.get("bucket", []byte("key"))
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	c.put("bucket", []byte("other"), []byte("value"))
+	require.NoError(t, c.commit())
+	value, err = store.Get("bucket", []byte("key"))
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+	value, err = store.Get("bucket", []byte("other"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
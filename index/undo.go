@@ -0,0 +1,203 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ReorgDepth is the number of most recent blocks that keep an undo log and
+// can therefore be unwound.
+const ReorgDepth = 20
+
+var (
+	ErrReorg        = errors.New("block does not extend the indexed chain")
+	ErrReorgTooDeep = errors.New("reorg is deeper than the retained undo logs")
+)
+
+// Chain is the best chain an Index follows with Sync.
+type Chain interface {
+	TipHeight() (uint64, error)
+	BlockHash(height uint64) (*chainhash.Hash, error)
+	Block(hash *chainhash.Hash) (*wire.MsgBlock, error)
+}
+
+func heightKey(height uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, height)
+}
+
+// BlockHash returns the hash of the block indexed at height, or nil if the
+// index has not seen it.
+func (idx *Index) BlockHash(height uint64) (*chainhash.Hash, error) {
+	b, err := idx.store.Get(bucketHeightToBlockHash, heightKey(height))
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return chainhash.NewHash(b)
+}
+
+// Unwind reverts the blocks at height and above using their undo logs, so
+// that height is indexed next.
+func (idx *Index) Unwind(height uint64) error {
+	for idx.height > height {
+		h := idx.height - 1
+		key := heightKey(h)
+		b, err := idx.store.Get(bucketHeightToUndo, key)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("%w: no undo log for block %d", ErrReorgTooDeep, h)
+		}
+		undo, err := decodeUndo(b)
+		if err != nil {
+			return err
+		}
+		batch := idx.store.NewBatch()
+		for _, w := range undo {
+			if w.delete {
+				batch.Delete(w.bucket, w.key)
+			} else {
+				batch.Put(w.bucket, w.key, w.value)
+			}
+		}
+		batch.Delete(bucketHeightToUndo, key)
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+		if err := idx.load(); err != nil {
+			return err
+		}
+		idx.feed.unwound(idx.height)
+	}
+	return nil
+}
+
+// Sync indexes chain up to its tip, first unwinding any blocks that are no
+// longer part of it.
+func (idx *Index) Sync(chain Chain) error {
+	if err := idx.unwindReorg(chain); err != nil {
+		return err
+	}
+	tip, err := chain.TipHeight()
+	if err != nil {
+		return err
+	}
+	for idx.height <= tip {
+		hash, err := chain.BlockHash(idx.height)
+		if err != nil {
+			return err
+		}
+		block, err := chain.Block(hash)
+		if err != nil {
+			return err
+		}
+		err = idx.IndexBlock(idx.height, block)
+		if errors.Is(err, ErrReorg) {
+			err = idx.unwindReorg(chain)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unwindReorg unwinds to the last indexed block that chain still contains.
+func (idx *Index) unwindReorg(chain Chain) error {
+	tip, err := chain.TipHeight()
+	if err != nil {
+		return err
+	}
+	for height := idx.height; ; height-- {
+		if height == 0 {
+			return idx.Unwind(0)
+		}
+		indexed, err := idx.BlockHash(height - 1)
+		if err != nil {
+			return err
+		}
+		if indexed == nil {
+			return idx.Unwind(height)
+		}
+		if height-1 <= tip {
+			hash, err := chain.BlockHash(height - 1)
+			if err != nil {
+				return err
+			}
+			if hash.IsEqual(indexed) {
+				return idx.Unwind(height)
+			}
+		}
+		if idx.height-height >= ReorgDepth {
+			break
+		}
+	}
+	return fmt.Errorf("%w: no common ancestor within %d blocks", ErrReorgTooDeep, ReorgDepth)
+}
+
+// undoLog returns the writes that restore every key touched by c to its value
+// in the underlying store.
+func (c *cache) undoLog() ([]write, error) {
+	undo := make([]write, 0, len(c.order))
+	for _, w := range c.order {
+		prev, err := c.store.Get(w.bucket, w.key)
+		if err != nil {
+			return nil, err
+		}
+		undo = append(undo, write{bucket: w.bucket, key: w.key, value: prev, delete: prev == nil})
+	}
+	return undo, nil
+}
+
+func encodeUndo(undo []write) []byte {
+	var b []byte
+	for _, w := range undo {
+		b = append(b, byte(len(w.bucket)))
+		b = append(b, w.bucket...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(w.key)))
+		b = append(b, w.key...)
+		if w.delete {
+			b = append(b, 0)
+			continue
+		}
+		b = append(b, 1)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(w.value)))
+		b = append(b, w.value...)
+	}
+	return b
+}
+
+func decodeUndo(b []byte) ([]write, error) {
+	var undo []write
+	d := decoder{b: b}
+	for len(d.b) > 0 && d.err == nil {
+		var w write
+		w.bucket = string(d.take(int(d.byte())))
+		w.key = d.take(int(binary.BigEndian.Uint16(d.take(2))))
+		if d.byte() == 0 {
+			w.delete = true
+		} else {
+			w.value = d.take(int(d.uint32()))
+		}
+		undo = append(undo, w)
+	}
+	return undo, d.err
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"github.com/bxelab/runestone/blocksource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Every block source can feed Sync.
+var _ Chain = blocksource.BlockSource(nil)
+
+// mockChain is a best chain that tests can extend and reorganize.
+type mockChain struct {
+	blocks []*wire.MsgBlock
+}
+
+func (m *mockChain) TipHeight() (uint64, error) {
+	return uint64(len(m.blocks)) - 1, nil
+}
+
+func (m *mockChain) BlockHash(height uint64) (*chainhash.Hash, error) {
+	if height >= uint64(len(m.blocks)) {
+		return nil, errors.New("height above tip")
+	}
+	hash := m.blocks[height].BlockHash()
+	return &hash, nil
+}
+
+func (m *mockChain) Block(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	for _, block := range m.blocks {
+		if block.BlockHash() == *hash {
+			return block, nil
+		}
+	}
+	return nil, errors.New("unknown block")
+}
+
+// add appends a block holding txs, with nonce telling apart blocks of
+// competing branches.
+func (m *mockChain) add(nonce uint32, txs ...*wire.MsgTx) {
+	height := uint64(len(m.blocks))
+	block := &wire.MsgBlock{
+		Header:       wire.BlockHeader{Timestamp: time.Unix(int64(height), 0), Nonce: nonce},
+		Transactions: append([]*wire.MsgTx{coinbase(height)}, txs...),
+	}
+	if height > 0 {
+		block.Header.PrevBlock = m.blocks[height-1].BlockHash()
+	}
+	m.blocks = append(m.blocks, block)
+}
+
+func TestUnwindRestoresState(t *testing.T) {
+	c := newContext(t)
+	r := testRune()
+	etching, id := c.etch(&runestone.Etching{Rune: &r, Premine: u128P(1000)})
+	etched := c.index.NextHeight()
+
+	transfer := runestoneTx(t, &runestone.Runestone{
+		Edicts: []runestone.Edict{{ID: id, Amount: u128(400), Output: 2}},
+	}, []*wire.TxIn{spend(etching, 1)}, 2)
+	c.mine(transfer)
+	assert.Nil(t, c.balances(etching, 1))
+
+	require.NoError(t, c.index.Unwind(etched))
+	assert.Equal(t, etched, c.index.NextHeight())
+	assert.Equal(t, []Balance{{ID: id, Amount: u128(1000)}}, c.balances(etching, 1))
+	assert.Nil(t, c.balances(transfer, 1))
+	assert.Nil(t, c.balances(transfer, 2))
+
+	require.NoError(t, c.index.Unwind(id.Block))
+	assert.Nil(t, c.runeEntry(id))
+	assert.Nil(t, c.runeId(r))
+	assert.Nil(t, c.balances(etching, 1))
+	assert.Zero(t, c.index.Runes())
+
+	// the unwound heights can be indexed again
+	c.mine(etching)
+	assert.Equal(t, &id, c.runeId(r))
+}
+
+func TestUnwindTooDeep(t *testing.T) {
+	c := newContext(t)
+	for i := 0; i < ReorgDepth+2; i++ {
+		c.mine()
+	}
+	assert.ErrorIs(t, c.index.Unwind(0), ErrReorgTooDeep)
+	require.NoError(t, c.index.Unwind(2))
+	assert.Equal(t, uint64(2), c.index.NextHeight())
+}
+
+func TestIndexBlockRejectsForeignParent(t *testing.T) {
+	c := newContext(t)
+	c.mine()
+	block := c.block(1)
+	block.Header.PrevBlock = chainhash.Hash{1}
+	assert.ErrorIs(t, c.index.IndexBlock(1, block), ErrReorg)
+	assert.Equal(t, uint64(1), c.index.NextHeight())
+}
+
+func TestSyncFollowsReorg(t *testing.T) {
+	c := newContext(t)
+	chain := &mockChain{}
+	chain.add(0)
+	chain.add(0)
+	etching := runestoneTx(t, &runestone.Runestone{Etching: &runestone.Etching{Premine: u128P(5)}},
+		[]*wire.TxIn{wire.NewTxIn(&wire.OutPoint{Index: 3}, nil, nil)}, 1)
+	chain.add(0, etching)
+	chain.add(0)
+	require.NoError(t, c.index.Sync(chain))
+	id := runestone.RuneId{Block: 2, Tx: 1}
+	assert.NotNil(t, c.runeEntry(id))
+	assert.Equal(t, uint64(4), c.index.NextHeight())
+
+	// a longer branch forking off below the etching drops it
+	chain.blocks = chain.blocks[:2]
+	for i := 0; i < 3; i++ {
+		chain.add(1)
+	}
+	require.NoError(t, c.index.Sync(chain))
+	assert.Nil(t, c.runeEntry(id))
+	assert.Nil(t, c.balances(etching, 1))
+	assert.Zero(t, c.index.Runes())
+	assert.Equal(t, uint64(5), c.index.NextHeight())
+	hash, err := c.index.BlockHash(4)
+	require.NoError(t, err)
+	assert.Equal(t, chain.blocks[4].BlockHash(), *hash)
+
+	// a shorter branch is unwound to as well
+	chain.blocks = chain.blocks[:3]
+	chain.add(2, etching)
+	require.NoError(t, c.index.Sync(chain))
+	assert.Equal(t, uint64(4), c.index.NextHeight())
+	assert.Equal(t, []Balance{{ID: runestone.RuneId{Block: 3, Tx: 1}, Amount: u128(5)}}, c.balances(etching, 1))
+}
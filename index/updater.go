@@ -0,0 +1,290 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/bxelab/runestone"
+	"lukechampine.com/uint128"
+)
+
+// updater holds the state of the block being indexed.
+type updater struct {
+	index         *Index
+	cache         *cache
+	height        uint64
+	blockTime     int64
+	minimum       runestone.Rune
+	runes         uint64
+	reservedRunes uint64
+	burned        map[runestone.RuneId]uint128.Uint128
+	events        []Event
+}
+
+// indexRunes applies tx, whose artifact has already been deciphered.
+func (u *updater) indexRunes(txIndex uint32, tx *wire.MsgTx, txid chainhash.Hash, artifact *runestone.Artifact) error {
+	inputs, err := u.unallocated(tx)
+	if err != nil {
+		return err
+	}
+
+	source := EventTx{Height: u.height, Txid: txid}
+	minted := uint128.Zero
+	var etchedID *runestone.RuneId
+	if artifact != nil {
+		if artifact.Cenotaph != nil && artifact.Cenotaph.Flaw != nil {
+			u.events = append(u.events, CenotaphObserved{EventTx: source, Flaw: *artifact.Cenotaph.Flaw})
+		}
+
+		var mintedID *runestone.RuneId
+		if id := artifact.Mint(); id != nil {
+			var ok bool
+			minted, ok, err = u.mint(*id)
+			if err != nil {
+				return err
+			}
+			if ok {
+				mintedID = id
+			}
+		}
+
+		etched, err := u.etched(txIndex, tx, artifact)
+		if err != nil {
+			return err
+		}
+		if etched != nil {
+			etchedID = &etched.ID
+			u.createRuneEntry(txid, artifact, etched)
+			u.events = append(u.events, Etched{EventTx: source, ID: etched.ID})
+		}
+		if mintedID != nil {
+			u.events = append(u.events, Minted{EventTx: source, ID: *mintedID, Amount: minted})
+		}
+	}
+
+	allocation := runestone.Allocate(tx, artifact, inputs, minted, etchedID)
+	for vout, balances := range allocation.Outputs {
+		if len(balances) == 0 {
+			continue
+		}
+		list := make([]Balance, 0, len(balances))
+		for id, balance := range balances {
+			list = append(list, Balance{ID: id, Amount: balance})
+		}
+		sortBalances(list)
+		key := outpointKey(wire.OutPoint{Hash: txid, Index: uint32(vout)})
+		script := scriptKey(tx.TxOut[vout].PkScript)
+		u.cache.put(bucketOutpointToBalances, key, encodeBalances(list))
+		u.cache.put(bucketOutpointToScript, key, script)
+		u.cache.put(bucketScriptToOutpoint, scriptOutpointKey(script, key), []byte{})
+		for _, balance := range list {
+			u.events = append(u.events, Transferred{EventTx: source, Vout: uint32(vout), ID: balance.ID, Amount: balance.Amount})
+		}
+	}
+	burned := make([]Balance, 0, len(allocation.Burned))
+	for id, amount := range allocation.Burned {
+		u.burned[id] = u.burned[id].Add(amount)
+		burned = append(burned, Balance{ID: id, Amount: amount})
+	}
+	sortBalances(burned)
+	for _, balance := range burned {
+		u.events = append(u.events, Burned{EventTx: source, ID: balance.ID, Amount: balance.Amount})
+	}
+	return nil
+}
+
+// unallocated removes the balances of every spent outpoint and returns them
+// in input order.
+func (u *updater) unallocated(tx *wire.MsgTx) ([]runestone.Balances, error) {
+	inputs := make([]runestone.Balances, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		key := outpointKey(in.PreviousOutPoint)
+		b, err := u.cache.get(bucketOutpointToBalances, key)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			continue
+		}
+		balances, err := decodeBalances(b)
+		if err != nil {
+			return nil, err
+		}
+		u.cache.delete(bucketOutpointToBalances, key)
+		script, err := u.cache.get(bucketOutpointToScript, key)
+		if err != nil {
+			return nil, err
+		}
+		if script != nil {
+			u.cache.delete(bucketOutpointToScript, key)
+			u.cache.delete(bucketScriptToOutpoint, scriptOutpointKey(script, key))
+		}
+		inputs[i] = make(runestone.Balances, len(balances))
+		for _, balance := range balances {
+			inputs[i].Add(balance.ID, balance.Amount)
+		}
+	}
+	return inputs, nil
+}
+
+// mint returns the amount received by a mint of id and whether it succeeded.
+func (u *updater) mint(id runestone.RuneId) (uint128.Uint128, bool, error) {
+	entry, err := u.runeEntry(id)
+	if err != nil || entry == nil {
+		return uint128.Zero, false, err
+	}
+	amount, err := entry.Mintable(u.height)
+	if err != nil {
+		return uint128.Zero, false, nil
+	}
+	entry.Mints = entry.Mints.Add64(1)
+	u.putRuneEntry(id, entry)
+	return amount, true, nil
+}
+
+func (u *updater) runeEntry(id runestone.RuneId) (*runestone.RuneEntry, error) {
+	b, err := u.cache.get(bucketRuneIdToEntry, runeIdKey(id))
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return decodeRuneEntry(b)
+}
+
+func (u *updater) putRuneEntry(id runestone.RuneId, entry *runestone.RuneEntry) {
+	u.cache.put(bucketRuneIdToEntry, runeIdKey(id), encodeRuneEntry(entry))
+}
+
+type etching struct {
+	ID   runestone.RuneId
+	Rune runestone.Rune
+}
+
+// etched returns the rune created by artifact, or nil if it does not etch a
+// valid one.
+func (u *updater) etched(txIndex uint32, tx *wire.MsgTx, artifact *runestone.Artifact) (*etching, error) {
+	r := artifact.EtchedRune(u.height, txIndex)
+	if r == nil {
+		return nil, nil
+	}
+
+	if artifact.Runestone != nil && artifact.Runestone.Etching.Rune == nil {
+		u.reservedRunes++
+	} else {
+		if r.Value.Cmp(u.minimum.Value) < 0 || r.IsReserved() {
+			return nil, nil
+		}
+		existing, err := u.cache.get(bucketRuneToRuneId, runeKey(*r))
+		if err != nil || existing != nil {
+			return nil, err
+		}
+		commits, err := u.txCommitsToRune(tx, *r)
+		if err != nil || !commits {
+			return nil, err
+		}
+	}
+
+	return &etching{
+		ID:   runestone.RuneId{Block: u.height, Tx: txIndex},
+		Rune: *r,
+	}, nil
+}
+
+// txCommitsToRune reports whether tx reveals a mature commitment to r,
+// resolving the outputs it spends with the TxLookup of the index.
+func (u *updater) txCommitsToRune(tx *wire.MsgTx, r runestone.Rune) (bool, error) {
+	lookup := &commitLookup{lookup: u.index.lookup}
+	err := u.index.chain.ValidateRuneCommitment(tx, r, lookup, u.height, lookup.confirmationHeight)
+	if lookup.err != nil {
+		return false, lookup.err
+	}
+	return err == nil, nil
+}
+
+// commitLookup adapts a TxLookup to the lookups of
+// runestone.ValidateRuneCommitment, keeping the first error it returns.
+type commitLookup struct {
+	lookup TxLookup
+	err    error
+
+	// the last outpoint, which is looked up for its script and then its height
+	outpoint wire.OutPoint
+	out      *wire.TxOut
+	height   uint64
+}
+
+func (l *commitLookup) FetchPrevOutput(outpoint wire.OutPoint) *wire.TxOut {
+	out, _, err := l.txOut(outpoint)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func (l *commitLookup) confirmationHeight(outpoint wire.OutPoint) (uint64, error) {
+	_, height, err := l.txOut(outpoint)
+	return height, err
+}
+
+func (l *commitLookup) txOut(outpoint wire.OutPoint) (*wire.TxOut, uint64, error) {
+	if l.err != nil {
+		return nil, 0, l.err
+	}
+	if l.lookup == nil {
+		l.err = ErrNoTxLookup
+		return nil, 0, l.err
+	}
+	if l.out != nil && l.outpoint == outpoint {
+		return l.out, l.height, nil
+	}
+	out, height, err := l.lookup.TxOut(outpoint)
+	if err != nil {
+		l.err = err
+		return nil, 0, err
+	}
+	l.outpoint, l.out, l.height = outpoint, out, height
+	return out, height, nil
+}
+
+func (u *updater) createRuneEntry(txid chainhash.Hash, artifact *runestone.Artifact, etched *etching) {
+	u.cache.put(bucketRuneToRuneId, runeKey(etched.Rune), runeIdKey(etched.ID))
+	u.cache.put(bucketTxidToRune, txid.CloneBytes(), runeKey(etched.Rune))
+	number := u.runes
+	u.runes++
+
+	entry := &runestone.RuneEntry{
+		Block:      etched.ID.Block,
+		Etching:    txid,
+		Number:     number,
+		SpacedRune: runestone.SpacedRune{Rune: etched.Rune},
+		Timestamp:  u.blockTime,
+	}
+	if artifact.Runestone != nil {
+		e := artifact.Runestone.Etching
+		if e.Divisibility != nil {
+			entry.Divisibility = *e.Divisibility
+		}
+		if e.Premine != nil {
+			entry.Premine = *e.Premine
+		}
+		if e.Spacers != nil {
+			entry.SpacedRune.Spacers = *e.Spacers
+		}
+		entry.Symbol = e.Symbol
+		entry.Terms = e.Terms
+		entry.Turbo = e.Turbo
+	}
+	u.putRuneEntry(etched.ID, entry)
+}
@@ -0,0 +1,208 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"encoding/json"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"lukechampine.com/uint128"
+)
+
+// The JSON forms follow ord: snake_case keys, uint128 amounts as decimal
+// strings since they do not fit in a JSON number, rune ids as "block:tx" and
+// runes by name.
+
+// decimalText is a uint128.Uint128 that is encoded as a decimal string.
+type decimalText uint128.Uint128
+
+func (d decimalText) MarshalText() ([]byte, error) {
+	return []byte(uint128.Uint128(d).String()), nil
+}
+
+func (d *decimalText) UnmarshalText(text []byte) error {
+	n, err := uint128.FromString(string(text))
+	if err != nil {
+		return err
+	}
+	*d = decimalText(n)
+	return nil
+}
+
+type edictJSON struct {
+	ID     RuneId      `json:"id"`
+	Amount decimalText `json:"amount"`
+	Output uint32      `json:"output"`
+}
+
+func (e Edict) MarshalJSON() ([]byte, error) {
+	return json.Marshal(edictJSON{ID: e.ID, Amount: decimalText(e.Amount), Output: e.Output})
+}
+
+func (e *Edict) UnmarshalJSON(data []byte) error {
+	var v edictJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = Edict{ID: v.ID, Amount: uint128.Uint128(v.Amount), Output: v.Output}
+	return nil
+}
+
+type termsJSON struct {
+	Amount *decimalText `json:"amount"`
+	Cap    *decimalText `json:"cap"`
+	Height [2]*uint64   `json:"height"`
+	Offset [2]*uint64   `json:"offset"`
+}
+
+func (t Terms) MarshalJSON() ([]byte, error) {
+	return json.Marshal(termsJSON{
+		Amount: (*decimalText)(t.Amount),
+		Cap:    (*decimalText)(t.Cap),
+		Height: t.Height,
+		Offset: t.Offset,
+	})
+}
+
+func (t *Terms) UnmarshalJSON(data []byte) error {
+	var v termsJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*t = Terms{
+		Amount: (*uint128.Uint128)(v.Amount),
+		Cap:    (*uint128.Uint128)(v.Cap),
+		Height: v.Height,
+		Offset: v.Offset,
+	}
+	return nil
+}
+
+type etchingJSON struct {
+	Divisibility *uint8       `json:"divisibility"`
+	Premine      *decimalText `json:"premine"`
+	Rune         *Rune        `json:"rune"`
+	Spacers      *uint32      `json:"spacers"`
+	Symbol       *string      `json:"symbol"`
+	Terms        *Terms       `json:"terms"`
+	Turbo        bool         `json:"turbo"`
+}
+
+var ErrSymbol = errors.New("symbol must be a single character")
+
+func (e Etching) MarshalJSON() ([]byte, error) {
+	v := etchingJSON{
+		Divisibility: e.Divisibility,
+		Premine:      (*decimalText)(e.Premine),
+		Rune:         e.Rune,
+		Spacers:      e.Spacers,
+		Terms:        e.Terms,
+		Turbo:        e.Turbo,
+	}
+	if e.Symbol != nil {
+		symbol := string(*e.Symbol)
+		v.Symbol = &symbol
+	}
+	return json.Marshal(v)
+}
+
+func (e *Etching) UnmarshalJSON(data []byte) error {
+	var v etchingJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = Etching{
+		Divisibility: v.Divisibility,
+		Premine:      (*uint128.Uint128)(v.Premine),
+		Rune:         v.Rune,
+		Spacers:      v.Spacers,
+		Terms:        v.Terms,
+		Turbo:        v.Turbo,
+	}
+	if v.Symbol != nil {
+		symbol, size := utf8.DecodeRuneInString(*v.Symbol)
+		if size == 0 || size != len(*v.Symbol) {
+			return ErrSymbol
+		}
+		e.Symbol = &symbol
+	}
+	return nil
+}
+
+type runeEntryJSON struct {
+	Block        uint64         `json:"block"`
+	Burned       decimalText    `json:"burned"`
+	Divisibility uint8          `json:"divisibility"`
+	Etching      chainhash.Hash `json:"etching"`
+	Mints        decimalText    `json:"mints"`
+	Number       uint64         `json:"number"`
+	Premine      decimalText    `json:"premine"`
+	SpacedRune   SpacedRune     `json:"spaced_rune"`
+	Symbol       *string        `json:"symbol"`
+	Terms        *Terms         `json:"terms"`
+	Timestamp    int64          `json:"timestamp"`
+	Turbo        bool           `json:"turbo"`
+}
+
+func (e RuneEntry) MarshalJSON() ([]byte, error) {
+	v := runeEntryJSON{
+		Block:        e.Block,
+		Burned:       decimalText(e.Burned),
+		Divisibility: e.Divisibility,
+		Etching:      e.Etching,
+		Mints:        decimalText(e.Mints),
+		Number:       e.Number,
+		Premine:      decimalText(e.Premine),
+		SpacedRune:   e.SpacedRune,
+		Terms:        e.Terms,
+		Timestamp:    e.Timestamp,
+		Turbo:        e.Turbo,
+	}
+	if e.Symbol != nil {
+		symbol := string(*e.Symbol)
+		v.Symbol = &symbol
+	}
+	return json.Marshal(v)
+}
+
+func (e *RuneEntry) UnmarshalJSON(data []byte) error {
+	var v runeEntryJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = RuneEntry{
+		Block:        v.Block,
+		Burned:       uint128.Uint128(v.Burned),
+		Divisibility: v.Divisibility,
+		Etching:      v.Etching,
+		Mints:        uint128.Uint128(v.Mints),
+		Number:       v.Number,
+		Premine:      uint128.Uint128(v.Premine),
+		SpacedRune:   v.SpacedRune,
+		Terms:        v.Terms,
+		Timestamp:    v.Timestamp,
+		Turbo:        v.Turbo,
+	}
+	if v.Symbol != nil {
+		symbol, size := utf8.DecodeRuneInString(*v.Symbol)
+		if size == 0 || size != len(*v.Symbol) {
+			return ErrSymbol
+		}
+		e.Symbol = &symbol
+	}
+	return nil
+}
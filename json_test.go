@@ -0,0 +1,135 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/assert"
+	"lukechampine.com/uint128"
+)
+
+func assertJSONRoundTrip[T any](t *testing.T, v T, expected string) {
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(b))
+	var decoded T
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, v, decoded)
+}
+
+func TestRunestoneJSON(t *testing.T) {
+	r := Rune{Value: uint128.From64(2055900680524219742)}
+	assertJSONRoundTrip(t, &Artifact{Runestone: &Runestone{
+		Edicts: []Edict{{ID: RuneId{Block: 840000, Tx: 1}, Amount: uint128.Max, Output: 2}},
+		Etching: &Etching{
+			Divisibility: Uint8P(2),
+			Premine:      Uint128PFrom64(1000),
+			Rune:         &r,
+			Spacers:      Uint32P(128),
+			Symbol:       CharP('⧉'),
+			Terms: &Terms{
+				Amount: Uint128PFrom64(1),
+				Cap:    Uint128P(uint128.Max),
+				Height: [2]*uint64{Uint64P(840000), nil},
+				Offset: [2]*uint64{nil, Uint64P(100)},
+			},
+			Turbo: true,
+		},
+		Mint:    &RuneId{Block: 1, Tx: 0},
+		Pointer: Uint32P(1),
+	}}, `{"runestone":{
+		"edicts":[{"id":"840000:1","amount":"340282366920938463463374607431768211455","output":2}],
+		"etching":{
+			"divisibility":2,
+			"premine":"1000",
+			"rune":"UNCOMMONGOODS",
+			"spacers":128,
+			"symbol":"⧉",
+			"terms":{"amount":"1","cap":"340282366920938463463374607431768211455","height":[840000,null],"offset":[null,100]},
+			"turbo":true
+		},
+		"mint":"1:0",
+		"pointer":1
+	}}`)
+
+	assertJSONRoundTrip(t, &Artifact{Runestone: &Runestone{}}, `{"runestone":{"edicts":null,"etching":null,"mint":null,"pointer":null}}`)
+	assertJSONRoundTrip(t, &Artifact{Cenotaph: &Cenotaph{Etching: &r, Flaw: FlawP(UnrecognizedEvenTag), Mint: &RuneId{Block: 1, Tx: 0}}},
+		`{"cenotaph":{"etching":"UNCOMMONGOODS","flaw":"unrecognized_even_tag","mint":"1:0"}}`)
+}
+
+func TestRuneEntryJSON(t *testing.T) {
+	symbol := '⧉'
+	etching, _ := chainhash.NewHashFromStr("2bb85f4b004be6da54f766c17c1e855187327112c231ef2ff35ebad0ea67c69e")
+	assertJSONRoundTrip(t, RuneEntry{
+		Block:        1,
+		Burned:       uint128.From64(123),
+		Divisibility: 0,
+		Etching:      *etching,
+		Mints:        uint128.From64(7),
+		Number:       0,
+		Premine:      uint128.Zero,
+		SpacedRune:   SpacedRune{Rune: Rune{Value: uint128.From64(2055900680524219742)}, Spacers: 128},
+		Symbol:       &symbol,
+		Terms:        &Terms{Amount: Uint128PFrom64(1), Cap: Uint128P(uint128.Max), Height: [2]*uint64{Uint64P(840000), Uint64P(1050000)}},
+		Timestamp:    1713571767,
+		Turbo:        true,
+	}, `{
+		"block":1,
+		"burned":"123",
+		"divisibility":0,
+		"etching":"2bb85f4b004be6da54f766c17c1e855187327112c231ef2ff35ebad0ea67c69e",
+		"mints":"7",
+		"number":0,
+		"premine":"0",
+		"spaced_rune":"UNCOMMON•GOODS",
+		"symbol":"⧉",
+		"terms":{"amount":"1","cap":"340282366920938463463374607431768211455","height":[840000,1050000],"offset":[null,null]},
+		"timestamp":1713571767,
+		"turbo":true
+	}`)
+}
+
+func TestTextMarshalling(t *testing.T) {
+	assertJSONRoundTrip(t, RuneId{Block: 2585359, Tx: 84}, `"2585359:84"`)
+	assertJSONRoundTrip(t, SpacedRune{Rune: Rune{Value: uint128.From64(2055900680524219742)}, Spacers: 128}, `"UNCOMMON•GOODS"`)
+	assertJSONRoundTrip(t, Rune{Value: uint128.Max}, `"BCGDENLQRQWDSLRUGSNLBTMFIJAV"`)
+	assertJSONRoundTrip(t, map[RuneId]Flaw{{Block: 1, Tx: 2}: Varint}, `{"1:2":"varint"}`)
+
+	for flaw := EdictOutput; flaw <= Varint; flaw++ {
+		text, err := flaw.MarshalText()
+		assert.NoError(t, err)
+		var decoded Flaw
+		assert.NoError(t, decoded.UnmarshalText(text))
+		assert.Equal(t, flaw, decoded)
+	}
+	_, err := Flaw(-1).MarshalText()
+	assert.Error(t, err)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var id RuneId
+	assert.Error(t, json.Unmarshal([]byte(`"1"`), &id))
+	var flaw Flaw
+	assert.Error(t, json.Unmarshal([]byte(`"bad_flaw"`), &flaw))
+	var r Rune
+	assert.Error(t, json.Unmarshal([]byte(`"abc"`), &r))
+	var edict Edict
+	assert.Error(t, json.Unmarshal([]byte(`{"id":"1:1","amount":"-1","output":0}`), &edict))
+	var etching Etching
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"symbol":"ab"}`), &etching), ErrSymbol)
+}
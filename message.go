@@ -26,9 +26,24 @@ type Message struct {
 }
 
 func MessageFromIntegers(tx *wire.MsgTx, payload []uint128.Uint128) (*Message, error) {
+	message, _ := messageFromIntegers(tx, payload)
+	return message, nil
+}
+
+// messagePositions tells where the parts of a message are among the integers
+// it was read from.
+type messagePositions struct {
+	// fields holds the index of the tag of every value in Message.Fields.
+	fields map[Tag][]int
+	// flaw is the index of the integer that caused Message.Flaw.
+	flaw int
+}
+
+func messageFromIntegers(tx *wire.MsgTx, payload []uint128.Uint128) (*Message, *messagePositions) {
 	var edicts []Edict
 	fields := make(map[Tag][]uint128.Uint128)
 	var flaw *Flaw
+	positions := &messagePositions{fields: make(map[Tag][]int), flaw: -1}
 
 	for i := 0; i < len(payload); i += 2 {
 		tag := Tag(payload[i].Lo)
@@ -38,6 +53,7 @@ func MessageFromIntegers(tx *wire.MsgTx, payload []uint128.Uint128) (*Message, e
 			for j := i + 1; j < len(payload); j += 4 {
 				if j+3 >= len(payload) {
 					flaw = FlawP(TrailingIntegers)
+					positions.flaw = j
 					break
 				}
 
@@ -45,12 +61,14 @@ func MessageFromIntegers(tx *wire.MsgTx, payload []uint128.Uint128) (*Message, e
 				next, err := id.Next(chunk[0], chunk[1])
 				if err != nil {
 					flaw = FlawP(EdictRuneId)
+					positions.flaw = j
 					break
 				}
 
 				edict, err := EdictFromIntegers(tx, *next, chunk[2], chunk[3])
 				if err != nil {
 					flaw = FlawP(EdictOutput)
+					positions.flaw = j + 3
 					break
 				}
 
@@ -63,8 +81,10 @@ func MessageFromIntegers(tx *wire.MsgTx, payload []uint128.Uint128) (*Message, e
 		if i+1 < len(payload) {
 			value := payload[i+1]
 			fields[tag] = append(fields[tag], value)
+			positions.fields[tag] = append(positions.fields[tag], i)
 		} else {
 			flaw = FlawP(TruncatedField)
+			positions.flaw = i
 			break
 		}
 	}
@@ -73,7 +93,7 @@ func MessageFromIntegers(tx *wire.MsgTx, payload []uint128.Uint128) (*Message, e
 		Flaw:   flaw,
 		Edicts: edicts,
 		Fields: fields,
-	}, nil
+	}, positions
 }
 
 func (m *Message) takeFlags() uint128.Uint128 {
@@ -0,0 +1,260 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+)
+
+var ErrAllocationMismatch = errors.New("allocation does not match the input balances")
+
+// PayloadPlan is the smallest runestone found by OptimizePayload.
+type PayloadPlan struct {
+	Runestone *Runestone
+	// Script is the enciphered Runestone, to be added as the last output of
+	// the transaction.
+	Script []byte
+	// Order is the order of the outputs Script is for: output i of the
+	// transaction has to be output Order[i] of the one given to
+	// OptimizePayload. It is nil if the outputs are kept in place.
+	Order []int
+	// NaiveSize is the length of the script of a runestone with an edict for
+	// every rune of every output.
+	NaiveSize int
+}
+
+// Size returns the length of the script of p.
+func (p *PayloadPlan) Size() int {
+	return len(p.Script)
+}
+
+// OptimizePayload returns the smallest runestone that makes tx move the runes
+// held by its inputs, whose sum is inputs, to its outputs as desired, which
+// has the balances of every output of tx and must account for every input
+// rune.
+//
+// Like for PlanTransfer, tx holds every output but the runestone one, which
+// is to be added last. The runestone leaves out the pointer when the default
+// output can take what is left, lets the last edict of a rune take what is
+// left with an amount of zero, and uses edicts to every output when a rune is
+// spread evenly. If reorder is set, the outputs of tx may also be swapped so
+// that no pointer is needed, see PayloadPlan.Order.
+func OptimizePayload(tx *wire.MsgTx, inputs Balances, desired []Balances, reorder bool) (*PayloadPlan, error) {
+	if len(desired) != len(tx.TxOut) {
+		return nil, fmt.Errorf("%w: %d balances for %d outputs", ErrAllocationMismatch, len(desired), len(tx.TxOut))
+	}
+	totals := make(Balances)
+	allocations := make([]Balances, len(desired))
+	for vout, balances := range desired {
+		allocations[vout] = make(Balances)
+		for id, amount := range balances {
+			if amount.IsZero() {
+				continue
+			}
+			if isOpReturn(tx.TxOut[vout].PkScript) {
+				return nil, fmt.Errorf("%w: output %d burns %s", ErrAllocationMismatch, vout, id)
+			}
+			total, err := checkedAdd(totals[id], amount)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrAllocationMismatch, id)
+			}
+			totals[id] = total
+			allocations[vout][id] = amount
+		}
+	}
+	for id, amount := range inputs {
+		if !amount.IsZero() && totals[id] != amount {
+			return nil, fmt.Errorf("%w: %s has %s in inputs and %s in outputs", ErrAllocationMismatch, id, amount, totals[id])
+		}
+	}
+	for id := range totals {
+		if inputs[id] != totals[id] {
+			return nil, fmt.Errorf("%w: %s has %s in inputs and %s in outputs", ErrAllocationMismatch, id, inputs[id], totals[id])
+		}
+	}
+
+	ids := make([]RuneId, 0, len(totals))
+	for id := range totals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Cmp(ids[j]) < 0 })
+
+	naive := &Runestone{}
+	for _, id := range ids {
+		for vout, balances := range allocations {
+			if amount, ok := balances[id]; ok {
+				naive.Edicts = append(naive.Edicts, Edict{ID: id, Amount: amount, Output: uint32(vout)})
+			}
+		}
+	}
+	naiveScript, _ := naive.Encipher()
+	best := &PayloadPlan{Runestone: naive, Script: naiveScript, NaiveSize: len(naiveScript)}
+
+	consider := func(order []int) {
+		outputs := tx.Copy()
+		permuted := allocations
+		if order != nil {
+			permuted = make([]Balances, len(order))
+			for i, vout := range order {
+				outputs.TxOut[i] = tx.TxOut[vout]
+				permuted[i] = allocations[vout]
+			}
+		}
+		var destinations []uint32
+		for vout, out := range outputs.TxOut {
+			if !isOpReturn(out.PkScript) {
+				destinations = append(destinations, uint32(vout))
+			}
+		}
+		for _, pointer := range destinations {
+			r := optimizeEdicts(ids, permuted, destinations, pointer)
+			if pointer != destinations[0] {
+				r.Pointer = &pointer
+			}
+			script, _ := r.Encipher()
+			if len(script) >= best.Size() || !allocates(outputs, r, script, inputs, permuted) {
+				continue
+			}
+			best = &PayloadPlan{Runestone: r, Script: script, Order: order, NaiveSize: len(naiveScript)}
+		}
+	}
+
+	consider(nil)
+	if reorder {
+		first := firstNonOpReturn(tx)
+		for vout := range tx.TxOut {
+			if vout <= first || isOpReturn(tx.TxOut[vout].PkScript) {
+				continue
+			}
+			order := make([]int, len(tx.TxOut))
+			for i := range order {
+				order[i] = i
+			}
+			order[first], order[vout] = vout, first
+			consider(order)
+		}
+	}
+	return best, nil
+}
+
+// optimizeEdicts returns the runestone with the fewest edict bytes moving
+// every rune of ids to outputs, what is left going to pointer.
+func optimizeEdicts(ids []RuneId, outputs []Balances, destinations []uint32, pointer uint32) *Runestone {
+	split := uint32(len(outputs) + 1)
+	r := &Runestone{}
+	for _, id := range ids {
+		var held []uint32
+		for vout, balances := range outputs {
+			if _, ok := balances[id]; ok {
+				held = append(held, uint32(vout))
+			}
+		}
+		amount := func(vout uint32) uint128.Uint128 {
+			return outputs[vout][id]
+		}
+		explicit := func(except uint32) []Edict {
+			var edicts []Edict
+			for _, vout := range held {
+				if vout != except {
+					edicts = append(edicts, Edict{ID: id, Amount: amount(vout), Output: vout})
+				}
+			}
+			return edicts
+		}
+
+		// every output but one explicitly, the last one taking what is left
+		// either through the pointer or an edict with an amount of zero
+		candidates := [][]Edict{explicit(split)}
+		for _, vout := range held {
+			edicts := explicit(vout)
+			if vout != pointer {
+				edicts = append(edicts, Edict{ID: id, Output: vout})
+			}
+			candidates = append(candidates, edicts)
+		}
+
+		// the same amount to every output, what is left going to the pointer
+		if len(held) == len(destinations) && len(destinations) > 1 {
+			var total uint128.Uint128
+			for _, vout := range held {
+				total = total.Add(amount(vout))
+			}
+			n := uint64(len(destinations))
+			each, remainder := total.QuoRem64(n)
+			even := true
+			for i, vout := range destinations {
+				share := each
+				if uint64(i) < remainder {
+					share = share.Add64(1)
+				}
+				even = even && amount(vout) == share
+			}
+			if even {
+				candidates = append(candidates, []Edict{{ID: id, Output: split}})
+			}
+
+			other := destinations[0]
+			if other == pointer {
+				other = destinations[1]
+			}
+			share := amount(other)
+			same := amount(pointer).Cmp(share) >= 0
+			for _, vout := range destinations {
+				same = same && (vout == pointer || amount(vout) == share)
+			}
+			if same {
+				candidates = append(candidates, []Edict{{ID: id, Amount: share, Output: split}})
+			}
+		}
+
+		best := candidates[0]
+		for _, edicts := range candidates[1:] {
+			if edictsSize(edicts) < edictsSize(best) {
+				best = edicts
+			}
+		}
+		r.Edicts = append(r.Edicts, best...)
+	}
+	return r
+}
+
+// edictsSize returns the number of bytes edicts of a single rune take in a
+// payload, but for the delta of the rune id of the first one, which does not
+// depend on them.
+func edictsSize(edicts []Edict) int {
+	size := 0
+	for i, edict := range edicts {
+		if i > 0 {
+			size += 2
+		}
+		size += len(AppendUvarint128(nil, edict.Amount)) + len(EncodeUint32(edict.Output))
+	}
+	return size
+}
+
+// allocates reports whether the runestone r, enciphered as script, moves the
+// runes of inputs to outputs as desired.
+func allocates(tx *wire.MsgTx, r *Runestone, script []byte, inputs Balances, desired []Balances) bool {
+	tx = tx.Copy()
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	allocation := Allocate(tx, &Artifact{Runestone: r}, []Balances{inputs}, uint128.Zero, nil)
+	return len(allocation.Burned) == 0 && reflect.DeepEqual(allocation.Outputs[:len(desired)], desired)
+}
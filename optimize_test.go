@@ -0,0 +1,127 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+// checkPlan checks that plan moves inputs to the outputs of tx as desired.
+func checkPlan(t *testing.T, tx *wire.MsgTx, plan *PayloadPlan, inputs Balances, desired []Balances) {
+	tx = tx.Copy()
+	if plan.Order != nil {
+		outputs := make([]*wire.TxOut, len(plan.Order))
+		reordered := make([]Balances, len(plan.Order))
+		for i, vout := range plan.Order {
+			outputs[i] = tx.TxOut[vout]
+			reordered[i] = desired[vout]
+		}
+		tx.TxOut, desired = outputs, reordered
+	}
+	tx.AddTxOut(wire.NewTxOut(0, plan.Script))
+	artifact, err := (&Runestone{}).Decipher(tx)
+	require.NoError(t, err)
+	require.NotNil(t, artifact.Runestone)
+	assert.Equal(t, plan.Runestone, artifact.Runestone)
+	allocation := Allocate(tx, artifact, []Balances{inputs}, uint128.Zero, nil)
+	assert.Empty(t, allocation.Burned)
+	for vout, balances := range desired {
+		if balances == nil {
+			balances = Balances{}
+		}
+		assert.Equal(t, balances, allocation.Outputs[vout], vout)
+	}
+	assert.LessOrEqual(t, plan.Size(), plan.NaiveSize)
+}
+
+func TestOptimizePayload(t *testing.T) {
+	a := RuneId{Block: 840000, Tx: 7}
+	b := RuneId{Block: 840001, Tx: 3}
+	big := uint128.From64(21_000_000_00000000)
+
+	// the remainder of a rune goes to the pointer without an edict
+	tx := transferTx(nil, nil)
+	inputs := Balances{a: big}
+	desired := []Balances{{a: big.Sub64(1000)}, {a: uint128.From64(1000)}}
+	plan, err := OptimizePayload(tx, inputs, desired, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Runestone{Edicts: []Edict{{ID: a, Amount: uint128.From64(1000), Output: 1}}}, plan.Runestone)
+	assert.Less(t, plan.Size(), plan.NaiveSize)
+	checkPlan(t, tx, plan, inputs, desired)
+
+	// an edict with an amount of zero takes the remainder of a rune that
+	// does not go to the pointer
+	inputs = Balances{a: big, b: big}
+	desired = []Balances{{a: uint128.From64(1000), b: big.Sub64(5)}, {a: big.Sub64(1000), b: uint128.From64(5)}}
+	plan, err = OptimizePayload(tx, inputs, desired, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Runestone{Edicts: []Edict{
+		{ID: a, Amount: uint128.From64(1000), Output: 0},
+		{ID: a, Output: 1},
+		{ID: b, Amount: uint128.From64(5), Output: 1},
+	}}, plan.Runestone)
+	assert.Less(t, plan.Size(), plan.NaiveSize)
+	checkPlan(t, tx, plan, inputs, desired)
+
+	// a rune split evenly over every output takes a single edict
+	tx = transferTx(nil, nil, []byte{txscript.OP_RETURN}, nil)
+	inputs = Balances{a: uint128.From64(301)}
+	desired = []Balances{{a: uint128.From64(101)}, {a: uint128.From64(100)}, nil, {a: uint128.From64(100)}}
+	plan, err = OptimizePayload(tx, inputs, desired, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Runestone{Edicts: []Edict{{ID: a, Output: 5}}}, plan.Runestone)
+	checkPlan(t, tx, plan, inputs, desired)
+
+	// and so does the same amount to every output, the rest going to the
+	// pointer
+	inputs = Balances{a: uint128.From64(1_000_000), b: uint128.From64(5)}
+	desired = []Balances{{a: uint128.From64(1000)}, {a: uint128.From64(1000)}, nil, {a: uint128.From64(998_000), b: uint128.From64(5)}}
+	plan, err = OptimizePayload(tx, inputs, desired, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Runestone{Edicts: []Edict{{ID: a, Amount: uint128.From64(1000), Output: 5}}, Pointer: Uint32P(3)}, plan.Runestone)
+	checkPlan(t, tx, plan, inputs, desired)
+
+	// swapping the outputs makes the pointer unnecessary
+	plan, err = OptimizePayload(tx, inputs, desired, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 2, 0}, plan.Order)
+	assert.Nil(t, plan.Runestone.Pointer)
+	checkPlan(t, tx, plan, inputs, desired)
+}
+
+func TestOptimizePayloadMismatch(t *testing.T) {
+	a := RuneId{Block: 840000, Tx: 7}
+	tx := transferTx(nil, []byte{txscript.OP_RETURN})
+	inputs := Balances{a: uint128.From64(10)}
+
+	_, err := OptimizePayload(tx, inputs, []Balances{{a: uint128.From64(10)}}, false)
+	assert.ErrorIs(t, err, ErrAllocationMismatch)
+	_, err = OptimizePayload(tx, inputs, []Balances{{a: uint128.From64(9)}, nil}, false)
+	assert.ErrorIs(t, err, ErrAllocationMismatch)
+	_, err = OptimizePayload(tx, inputs, []Balances{{a: uint128.From64(10), {Block: 1}: uint128.From64(1)}, nil}, false)
+	assert.ErrorIs(t, err, ErrAllocationMismatch)
+	_, err = OptimizePayload(tx, inputs, []Balances{{a: uint128.From64(5)}, {a: uint128.From64(5)}}, false)
+	assert.ErrorIs(t, err, ErrAllocationMismatch)
+
+	plan, err := OptimizePayload(tx, inputs, []Balances{{a: uint128.From64(10)}, nil}, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Runestone{}, plan.Runestone)
+}
@@ -75,47 +75,39 @@ func (r Rune) N() uint128.Uint128 {
 
 const SUBSIDY_HALVING_INTERVAL uint32 = 210_000
 
+// FirstRuneHeight returns the first rune height of the registered chain
+// with network magic network, or 0 if there is none.
+//
+// Deprecated: use Chain.FirstRuneHeight, which does not mistake an
+// unregistered network for one where runes start at genesis.
 func FirstRuneHeight(network wire.BitcoinNet) uint32 {
-	var multiplier uint32
-	switch network {
-	case wire.MainNet:
-		multiplier = 4
-	case wire.TestNet, wire.SimNet:
-		multiplier = 0
-	case wire.TestNet3:
-		multiplier = 12
-	default:
-		multiplier = 0
-	}
-	return SUBSIDY_HALVING_INTERVAL * multiplier
+	return uint32(chainForNet(network).FirstRuneHeight)
 }
-func MinimumAtHeight(chain wire.BitcoinNet, height uint64) Rune {
-	offset := height + 1
-	const interval uint32 = SUBSIDY_HALVING_INTERVAL / 12
-	start := FirstRuneHeight(chain)
-	end := start + SUBSIDY_HALVING_INTERVAL
-	if offset < uint64(start) {
-		return Rune{STEPS[12]}
-	}
-	if offset >= uint64(end) {
-		return Rune{}
-	}
-	progress := offset - uint64(start)
-	length := 12 - progress/uint64(interval)
-	endStep := STEPS[length-1]
-	startStep := STEPS[length]
-	remainder := progress % uint64(interval)
 
-	//val := startStep - ((startStep - endStep) * remainder / uint64(interval))
-	val := startStep.Sub(startStep.Sub(endStep).Mul(uint128.From64(remainder)).Div(uint128.From64(uint64(interval))))
-	return Rune{val}
+// MinimumAtHeight returns Chain.MinimumAtHeight for the registered chain with
+// network magic chain, treating an unregistered network like regtest.
+//
+// Deprecated: use Chain.MinimumAtHeight.
+func MinimumAtHeight(chain wire.BitcoinNet, height uint64) Rune {
+	return chainForNet(chain).MinimumAtHeight(height)
+}
 
+// chainForNet returns the registered chain with network magic net, or Regtest
+// for the networks that FirstRuneHeight used to treat as height 0.
+func chainForNet(net wire.BitcoinNet) *Chain {
+	chain, err := ChainByNet(net)
+	if err != nil {
+		return Regtest
+	}
+	return chain
 }
 
 func (r Rune) IsReserved() bool {
 	return r.Value.Cmp(RESERVED) >= 0
 }
 
+// Reserved returns the rune assigned to an unnamed etching in the transaction
+// at index tx of the block at height block, RESERVED + (block << 32 | tx).
 func Reserved(block uint64, tx uint32) Rune {
 	v := RESERVED.Add(uint128.From64(block).Lsh(32).Or(uint128.From64(uint64(tx))))
 	return Rune{
@@ -203,3 +195,18 @@ func (e Error) Error() string {
 func (r Rune) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + r.String() + `"`), nil
 }
+
+// MarshalText encodes r as its name.
+func (r Rune) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a rune name.
+func (r *Rune) UnmarshalText(text []byte) error {
+	parsed, err := RuneFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"lukechampine.com/uint128"
+)
+
+// RuneEntry is the state of an etched rune, as kept by ord's index.
+type RuneEntry struct {
+	Block        uint64
+	Burned       uint128.Uint128
+	Divisibility uint8
+	Etching      chainhash.Hash
+	Mints        uint128.Uint128
+	Number       uint64
+	Premine      uint128.Uint128
+	SpacedRune   SpacedRune
+	Symbol       *rune
+	Terms        *Terms
+	Timestamp    int64
+	Turbo        bool
+}
+
+var (
+	ErrNoMintTerms    = errors.New("rune has no mint terms")
+	ErrMintNotStarted = errors.New("mint has not started")
+	ErrMintEnded      = errors.New("mint has ended")
+	ErrMintCapReached = errors.New("mint cap reached")
+)
+
+// Mintable returns the amount a mint in a block at height receives. The error
+// wraps ErrNoMintTerms, ErrMintNotStarted, ErrMintEnded or ErrMintCapReached
+// when the mint would not succeed, mirroring RuneEntry::mintable in ord.
+func (e *RuneEntry) Mintable(height uint64) (uint128.Uint128, error) {
+	if e.Terms == nil {
+		return uint128.Zero, ErrNoMintTerms
+	}
+	if start := e.Start(); start != nil && height < *start {
+		return uint128.Zero, fmt.Errorf("%w: starts at block %d", ErrMintNotStarted, *start)
+	}
+	if end := e.End(); end != nil && height >= *end {
+		return uint128.Zero, fmt.Errorf("%w: ended at block %d", ErrMintEnded, *end)
+	}
+	cap := uint128.Zero
+	if e.Terms.Cap != nil {
+		cap = *e.Terms.Cap
+	}
+	if e.Mints.Cmp(cap) >= 0 {
+		return uint128.Zero, fmt.Errorf("%w: %s mints", ErrMintCapReached, cap)
+	}
+	if e.Terms.Amount == nil {
+		return uint128.Zero, nil
+	}
+	return *e.Terms.Amount, nil
+}
+
+// Start returns the first height at which the rune can be minted, the later of
+// the absolute start height and the start offset from the etching block, or
+// nil if neither is set.
+func (e *RuneEntry) Start() *uint64 {
+	if e.Terms == nil {
+		return nil
+	}
+	var relative *uint64
+	if e.Terms.Offset[0] != nil {
+		h := saturatingAdd(e.Block, *e.Terms.Offset[0])
+		relative = &h
+	}
+	absolute := e.Terms.Height[0]
+	if relative != nil && absolute != nil {
+		h := max(*relative, *absolute)
+		return &h
+	}
+	if relative != nil {
+		return relative
+	}
+	return absolute
+}
+
+// End returns the first height at which the rune can no longer be minted, the
+// earlier of the absolute end height and the end offset from the etching
+// block, or nil if neither is set.
+func (e *RuneEntry) End() *uint64 {
+	if e.Terms == nil {
+		return nil
+	}
+	var relative *uint64
+	if e.Terms.Offset[1] != nil {
+		h := saturatingAdd(e.Block, *e.Terms.Offset[1])
+		relative = &h
+	}
+	absolute := e.Terms.Height[1]
+	if relative != nil && absolute != nil {
+		h := min(*relative, *absolute)
+		return &h
+	}
+	if relative != nil {
+		return relative
+	}
+	return absolute
+}
+
+// Supply returns the number of units premined and minted so far.
+func (e *RuneEntry) Supply() uint128.Uint128 {
+	amount := uint128.Zero
+	if e.Terms != nil && e.Terms.Amount != nil {
+		amount = *e.Terms.Amount
+	}
+	return e.Premine.Add(e.Mints.Mul(amount))
+}
+
+func saturatingAdd(a, b uint64) uint64 {
+	if a+b < a {
+		return ^uint64(0)
+	}
+	return a + b
+}
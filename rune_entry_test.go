@@ -0,0 +1,81 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"lukechampine.com/uint128"
+)
+
+func heightFrom(h uint64) *uint64 {
+	return &h
+}
+
+func TestMintable(t *testing.T) {
+	caseFunc := func(entry *RuneEntry, height uint64, expected uint128.Uint128, expectedErr error) {
+		amount, err := entry.Mintable(height)
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, expected, amount)
+	}
+
+	caseFunc(&RuneEntry{Block: 1}, 1, uint128.Zero, ErrNoMintTerms)
+	caseFunc(&RuneEntry{Block: 1, Terms: &Terms{}}, 1, uint128.Zero, ErrMintCapReached)
+	caseFunc(&RuneEntry{Block: 1, Terms: &Terms{Cap: uint128From(1)}}, 1, uint128.Zero, nil)
+	caseFunc(&RuneEntry{Block: 1, Terms: &Terms{Amount: uint128From(1000), Cap: uint128From(1)}}, 1, uint128.From64(1000), nil)
+
+	capped := &RuneEntry{Block: 1, Mints: uint128.From64(1), Terms: &Terms{Amount: uint128From(1000), Cap: uint128From(1)}}
+	caseFunc(capped, 1, uint128.Zero, ErrMintCapReached)
+
+	height := &RuneEntry{Block: 1, Terms: &Terms{Amount: uint128From(1), Cap: uint128From(1), Height: [2]*uint64{heightFrom(10), heightFrom(20)}}}
+	caseFunc(height, 9, uint128.Zero, ErrMintNotStarted)
+	caseFunc(height, 10, uint128.From64(1), nil)
+	caseFunc(height, 19, uint128.From64(1), nil)
+	caseFunc(height, 20, uint128.Zero, ErrMintEnded)
+
+	// offsets are relative to the etching block
+	offset := &RuneEntry{Block: 100, Terms: &Terms{Amount: uint128From(1), Cap: uint128From(1), Offset: [2]*uint64{heightFrom(10), heightFrom(20)}}}
+	caseFunc(offset, 109, uint128.Zero, ErrMintNotStarted)
+	caseFunc(offset, 110, uint128.From64(1), nil)
+	caseFunc(offset, 119, uint128.From64(1), nil)
+	caseFunc(offset, 120, uint128.Zero, ErrMintEnded)
+
+	// the later start and the earlier end win
+	both := &RuneEntry{Block: 100, Terms: &Terms{
+		Amount: uint128From(1),
+		Cap:    uint128From(1),
+		Height: [2]*uint64{heightFrom(105), heightFrom(130)},
+		Offset: [2]*uint64{heightFrom(10), heightFrom(20)},
+	}}
+	caseFunc(both, 109, uint128.Zero, ErrMintNotStarted)
+	caseFunc(both, 119, uint128.From64(1), nil)
+	caseFunc(both, 120, uint128.Zero, ErrMintEnded)
+	both.Terms.Height = [2]*uint64{heightFrom(115), heightFrom(118)}
+	caseFunc(both, 114, uint128.Zero, ErrMintNotStarted)
+	caseFunc(both, 117, uint128.From64(1), nil)
+	caseFunc(both, 118, uint128.Zero, ErrMintEnded)
+
+	overflow := &RuneEntry{Block: 100, Terms: &Terms{Amount: uint128From(1), Cap: uint128From(1), Offset: [2]*uint64{nil, heightFrom(math.MaxUint64)}}}
+	caseFunc(overflow, math.MaxUint64-1, uint128.From64(1), nil)
+	caseFunc(overflow, math.MaxUint64, uint128.Zero, ErrMintEnded)
+}
+
+func TestRuneEntrySupply(t *testing.T) {
+	entry := &RuneEntry{Premine: uint128.From64(5), Mints: uint128.From64(3), Terms: &Terms{Amount: uint128From(100)}}
+	assert.Equal(t, uint128.From64(305), entry.Supply())
+	assert.Equal(t, uint128.From64(5), (&RuneEntry{Premine: uint128.From64(5)}).Supply())
+}
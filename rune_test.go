@@ -19,6 +19,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/stretchr/testify/assert"
 	"lukechampine.com/uint128"
@@ -209,6 +210,36 @@ func TestReserved(t *testing.T) {
 	r2 := Rune{RESERVED.Add(uint128.From64(math.MaxUint64).Lsh(32).Or(uint128.From64(math.MaxUint32)))}
 	assertJsonEqual(t, r1, r2)
 }
+func TestEtchedRune(t *testing.T) {
+	decipher := func(script []byte) *Artifact {
+		tx := &wire.MsgTx{TxOut: []*wire.TxOut{{PkScript: script}}}
+		artifact, _ := (&Runestone{}).Decipher(tx)
+		return artifact
+	}
+	named := Rune{Value: uint128.From64(1000)}
+	script, _ := (&Runestone{Etching: &Etching{Rune: &named}}).Encipher()
+	assert.Equal(t, &named, decipher(script).EtchedRune(840000, 1))
+
+	unnamed, _ := (&Runestone{Etching: &Etching{}}).Encipher()
+	reserved := Reserved(840000, 1)
+	assert.Equal(t, &reserved, decipher(unnamed).EtchedRune(840000, 1))
+	block := &wire.MsgBlock{Transactions: []*wire.MsgTx{{}, {TxOut: []*wire.TxOut{{PkScript: unnamed}}}}}
+	artifacts := DecipherBlock(block)
+	assert.Len(t, artifacts, 1)
+	assert.Equal(t, &reserved, artifacts[0].EtchedRune(840000))
+
+	mint, _ := (&Runestone{Mint: &RuneId{Block: 1, Tx: 0}}).Encipher()
+	assert.Nil(t, decipher(mint).EtchedRune(840000, 1))
+
+	// a cenotaph only etches a rune it names
+	cenotaph := append(script, txscript.OP_DATA_2, byte(TagCenotaph), 0)
+	assert.NotNil(t, decipher(cenotaph).Cenotaph)
+	assert.Equal(t, &named, decipher(cenotaph).EtchedRune(840000, 1))
+	cenotaph = append(unnamed, txscript.OP_DATA_2, byte(TagCenotaph), 0)
+	assert.NotNil(t, decipher(cenotaph).Cenotaph)
+	assert.Nil(t, decipher(cenotaph).EtchedRune(840000, 1))
+}
+
 func TestIsReserved(t *testing.T) {
 	assert := assert.New(t)
 
@@ -104,6 +104,21 @@ func RuneIdFromString(s string) (*RuneId, error) {
 	return NewRuneId(block, uint32(tx))
 }
 
+// MarshalText encodes r as "block:tx".
+func (r RuneId) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a rune id in "block:tx" form.
+func (r *RuneId) UnmarshalText(text []byte) error {
+	parsed, err := RuneIdFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
 var (
 	ErrSeparator   = errors.New("missing separator")
 	ErrBlock       = func(err string) error { return fmt.Errorf("invalid Block height:%s", err) }
@@ -15,8 +15,8 @@
 package runestone
 
 import (
+	"bytes"
 	"errors"
-	"math/big"
 	"sort"
 	"unicode/utf8"
 
@@ -30,17 +30,26 @@ const (
 	COMMIT_CONFIRMATIONS = 6
 )
 
+var ErrNoRunestone = errors.New("no runestone output found")
+
 type Runestone struct {
-	Edicts  []Edict
-	Etching *Etching
-	Mint    *RuneId
-	Pointer *uint32
+	Edicts  []Edict  `json:"edicts"`
+	Etching *Etching `json:"etching"`
+	Mint    *RuneId  `json:"mint"`
+	Pointer *uint32  `json:"pointer"`
 }
 
 func (r *Runestone) Decipher(transaction *wire.MsgTx) (*Artifact, error) {
-	payload, err := r.payload(transaction)
+	return r.decipher(transaction, MAGIC_NUMBER, nil)
+}
+
+// decipher deciphers the runestone of transaction that follows magic, adding
+// every flaw it finds to diagnostics if that is not nil.
+func (r *Runestone) decipher(transaction *wire.MsgTx, magic byte, diagnostics *diagnoser) (*Artifact, error) {
+	payload, err := r.payload(transaction, magic)
 	if err != nil {
 		if payload != nil {
+			diagnostics.script(payload)
 			return &Artifact{
 				Cenotaph: &Cenotaph{
 					Flaw: &payload.Invalid,
@@ -49,18 +58,22 @@ func (r *Runestone) Decipher(transaction *wire.MsgTx) (*Artifact, error) {
 
 		return nil, err
 	}
+	diagnostics.setOutput(payload.output)
 
-	integers, err := r.integers(payload.Valid)
+	integers, offsets, err := decodeIntegers(payload.Valid)
 	if err != nil {
 		flaw := Varint
+		diagnostics.varint(integers, offsets)
 		return &Artifact{
 			Cenotaph: &Cenotaph{
 				Flaw: &flaw,
 			},
 		}, err
 	}
+	diagnostics.setIntegers(integers, offsets)
 
-	message, err := MessageFromIntegers(transaction, integers)
+	message, positions := messageFromIntegers(transaction, integers)
+	diagnostics.message(message, positions)
 	flags, err := TagTake(TagFlags, message.Fields,
 		func(uint128s []uint128.Uint128) (*uint128.Uint128, error) {
 			return &uint128s[0], nil
@@ -196,25 +209,29 @@ func (r *Runestone) Decipher(transaction *wire.MsgTx) (*Artifact, error) {
 	//      flaw.get_or_insert(Flaw::SupplyOverflow);
 	//    }
 	if etching != nil && etching.Supply() == nil {
-		message.Flaw = FlawP(SupplyOverflow)
-
+		if message.Flaw == nil {
+			message.Flaw = FlawP(SupplyOverflow)
+		}
+		diagnostics.supplyOverflow()
 	}
 	// if flags != 0 {
 	//      flaw.get_or_insert(Flaw::UnrecognizedFlag);
 	//    }
 	if !flags.IsZero() {
-		message.Flaw = FlawP(UnrecognizedFlag)
-
+		if message.Flaw == nil {
+			message.Flaw = FlawP(UnrecognizedFlag)
+		}
+		diagnostics.unrecognizedFlag(positions)
 	}
 	//    if fields.keys().any(|tag| tag % 2 == 0) {
 	//      flaw.get_or_insert(Flaw::UnrecognizedEvenTag);
 	//    }
 	for tag := range message.Fields {
-		if tag%2 == 0 {
+		if tag%2 == 0 && message.Flaw == nil {
 			message.Flaw = FlawP(UnrecognizedEvenTag)
 		}
-
 	}
+	diagnostics.unrecognizedEvenTags(message.Fields, positions)
 	//if let Some(flaw) = flaw {
 	//      return Some(Artifact::Cenotaph(Cenotaph {
 	//        flaw: Some(flaw),
@@ -246,8 +263,50 @@ func (r *Runestone) Decipher(transaction *wire.MsgTx) (*Artifact, error) {
 	}, nil
 }
 
+// EtchedRune returns the rune r etches when it is in the transaction at index
+// tx of the block at height block: the name given by its etching, or if it
+// has none the reserved rune for that position. It returns nil if r has no
+// etching.
+func (r *Runestone) EtchedRune(block uint64, tx uint32) *Rune {
+	if r.Etching == nil {
+		return nil
+	}
+	if r.Etching.Rune != nil {
+		return r.Etching.Rune
+	}
+	reserved := Reserved(block, tx)
+	return &reserved
+}
+
+// Encipher returns the OP_RETURN script of r, byte for byte the script ord's
+// Runestone::encipher produces: tags in ord's order, optional fields only
+// when set, edicts sorted by rune id and the payload pushed in chunks of at
+// most MaxScriptElementSize bytes.
 func (r *Runestone) Encipher() ([]byte, error) {
+	return encipherScript(r.encipherPayload(), MAGIC_NUMBER), nil
+}
+
+// Canonical reports whether the runestone output of tx is exactly the script
+// Encipher produces for the runestone it deciphers to. Unknown odd tags,
+// unsorted edicts, overlong varints or different push sizes all make it
+// non-canonical, and so does a cenotaph.
+func Canonical(tx *wire.MsgTx) (bool, error) {
+	script := runestoneScript(tx, MAGIC_NUMBER)
+	if script == nil {
+		return false, ErrNoRunestone
+	}
+	artifact, _ := (&Runestone{}).Decipher(tx)
+	if artifact == nil || artifact.Runestone == nil {
+		return false, nil
+	}
+	enciphered, err := artifact.Runestone.Encipher()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(script, enciphered), nil
+}
 
+func (r *Runestone) encipherPayload() []byte {
 	var payload []byte
 	//Etching
 	if r.Etching != nil {
@@ -260,10 +319,10 @@ func (r *Runestone) Encipher() ([]byte, error) {
 			FlagTurbo.Set(&flags)
 		}
 		payload = append(payload, TagFlags.Byte())
-		payload = append(payload, EncodeUint128(flags)...)
+		payload = AppendUvarint128(payload, flags)
 		if r.Etching.Rune != nil {
 			payload = append(payload, TagRune.Byte())
-			payload = append(payload, EncodeUint128(r.Etching.Rune.Value)...)
+			payload = AppendUvarint128(payload, r.Etching.Rune.Value)
 		}
 		if r.Etching.Divisibility != nil {
 			payload = append(payload, TagDivisibility.Byte())
@@ -279,28 +338,32 @@ func (r *Runestone) Encipher() ([]byte, error) {
 		}
 		if r.Etching.Premine != nil {
 			payload = append(payload, TagPremine.Byte())
-			payload = append(payload, EncodeUint128(*r.Etching.Premine)...)
+			payload = AppendUvarint128(payload, *r.Etching.Premine)
 		}
-		if r.Etching.Terms != nil {
-			payload = append(payload, TagAmount.Byte())
-			payload = append(payload, EncodeUint128(*r.Etching.Terms.Amount)...)
-			payload = append(payload, TagCap.Byte())
-			payload = append(payload, EncodeUint128(*r.Etching.Terms.Cap)...)
-			if r.Etching.Terms.Height[0] != nil {
+		if terms := r.Etching.Terms; terms != nil {
+			if terms.Amount != nil {
+				payload = append(payload, TagAmount.Byte())
+				payload = AppendUvarint128(payload, *terms.Amount)
+			}
+			if terms.Cap != nil {
+				payload = append(payload, TagCap.Byte())
+				payload = AppendUvarint128(payload, *terms.Cap)
+			}
+			if terms.Height[0] != nil {
 				payload = append(payload, TagHeightStart.Byte())
-				payload = append(payload, EncodeUint64(*r.Etching.Terms.Height[0])...)
+				payload = append(payload, EncodeUint64(*terms.Height[0])...)
 			}
-			if r.Etching.Terms.Height[1] != nil {
+			if terms.Height[1] != nil {
 				payload = append(payload, TagHeightEnd.Byte())
-				payload = append(payload, EncodeUint64(*r.Etching.Terms.Height[1])...)
+				payload = append(payload, EncodeUint64(*terms.Height[1])...)
 			}
-			if r.Etching.Terms.Offset[0] != nil {
+			if terms.Offset[0] != nil {
 				payload = append(payload, TagOffsetStart.Byte())
-				payload = append(payload, EncodeUint64(*r.Etching.Terms.Offset[0])...)
+				payload = append(payload, EncodeUint64(*terms.Offset[0])...)
 			}
-			if r.Etching.Terms.Offset[1] != nil {
+			if terms.Offset[1] != nil {
 				payload = append(payload, TagOffsetEnd.Byte())
-				payload = append(payload, EncodeUint64(*r.Etching.Terms.Offset[1])...)
+				payload = append(payload, EncodeUint64(*terms.Offset[1])...)
 			}
 		}
 	}
@@ -319,73 +382,82 @@ func (r *Runestone) Encipher() ([]byte, error) {
 	//Edicts
 	if len(r.Edicts) != 0 {
 		payload = append(payload, TagBody.Byte())
-		edicts := r.Edicts
-		sort.Slice(edicts, func(i, j int) bool {
-			if edicts[i].ID.Block < (edicts[j].ID.Block) {
-				return true
-			}
-			if edicts[i].ID.Block == edicts[j].ID.Block && edicts[i].ID.Block < edicts[j].ID.Block {
-				return true
-			}
-			return false
+		// sort a copy, stable like ord's sort_by_key, leaving r untouched
+		edicts := make([]Edict, len(r.Edicts))
+		copy(edicts, r.Edicts)
+		sort.SliceStable(edicts, func(i, j int) bool {
+			return edicts[i].ID.Cmp(edicts[j].ID) < 0
 		})
 
 		var previous = RuneId{0, 0}
 		for _, edict := range edicts {
-			temp := RuneId{edict.ID.Block, edict.ID.Tx}
 			block, tx, _ := previous.Delta(edict.ID)
 			payload = append(payload, EncodeUint64(block)...)
 			payload = append(payload, EncodeUint32(tx)...)
-			payload = append(payload, EncodeUint128(edict.Amount)...)
+			payload = AppendUvarint128(payload, edict.Amount)
 			payload = append(payload, EncodeUint32(edict.Output)...)
-			previous = temp
+			previous = edict.ID
 		}
 	}
+	return payload
+}
 
-	//build op_return script
-	builder := txscript.NewScriptBuilder()
-	// Push OP_RETURN
-	builder.AddOp(txscript.OP_RETURN)
-	// Push MAGIC_NUMBER
-	builder.AddOp(MAGIC_NUMBER)
+// encipherScript builds an OP_RETURN magic script pushing payload.
+// Chunks are pushed as plain data like rust-bitcoin's push_slice does:
+// txscript.ScriptBuilder would turn a one byte chunk such as 0x01 into OP_1,
+// which Decipher rejects as a non-pushdata opcode.
+func encipherScript(payload []byte, magic byte) []byte {
+	script := make([]byte, 0, 2+len(payload)+(len(payload)/txscript.MaxScriptElementSize+1)*3)
+	script = append(script, txscript.OP_RETURN, magic)
 	for len(payload) > 0 {
-		chunkSize := txscript.MaxScriptElementSize
-		if len(payload) < chunkSize {
-			chunkSize = len(payload)
+		chunk := payload[:min(len(payload), txscript.MaxScriptElementSize)]
+		switch n := len(chunk); {
+		case n < txscript.OP_PUSHDATA1:
+			script = append(script, byte(n))
+		case n <= 0xff:
+			script = append(script, txscript.OP_PUSHDATA1, byte(n))
+		default:
+			script = append(script, txscript.OP_PUSHDATA2, byte(n), byte(n>>8))
 		}
-		chunk := payload[:chunkSize]
-		builder.AddData(chunk)
-		payload = payload[chunkSize:]
+		script = append(script, chunk...)
+		payload = payload[len(chunk):]
 	}
-	return builder.Script()
+	return script
 }
 
 type Payload struct {
 	Valid   []byte
 	Invalid Flaw
+
+	// output is the index of the runestone output, and offset that of the
+	// instruction making an Invalid payload in its script.
+	output int
+	offset int
 }
 
-func (r *Runestone) payload(transaction *wire.MsgTx) (*Payload, error) {
-	for _, output := range transaction.TxOut {
+func (r *Runestone) payload(transaction *wire.MsgTx, magic byte) (*Payload, error) {
+	for vout, output := range transaction.TxOut {
 		tokenizer := txscript.MakeScriptTokenizer(0, output.PkScript)
 		if !tokenizer.Next() || tokenizer.Err() != nil || tokenizer.Opcode() != txscript.OP_RETURN {
 			// Check for OP_RETURN
 			continue
 		}
-		if !tokenizer.Next() || tokenizer.Err() != nil || tokenizer.Opcode() != MAGIC_NUMBER {
+		if !tokenizer.Next() || tokenizer.Err() != nil || tokenizer.Opcode() != magic {
 			// Check for protocol identifier (Runestone::MAGIC_NUMBER)
 			continue
 		}
 
 		// Construct the payload by concatenating remaining data pushes
 		var payload []byte
+		offset := int(tokenizer.ByteIndex())
 		for tokenizer.Next() {
 			//is PushBytes
 			if isPushBytes(tokenizer.Opcode()) {
 				payload = append(payload, tokenizer.Data()...)
+				offset = int(tokenizer.ByteIndex())
 				continue
 			} else {
-				return &Payload{Invalid: Opcode}, Opcode.Error()
+				return &Payload{Invalid: Opcode, output: vout, offset: offset}, Opcode.Error()
 			}
 
 		}
@@ -393,10 +465,10 @@ func (r *Runestone) payload(transaction *wire.MsgTx) (*Payload, error) {
 		//            return Some(Payload::Invalid(Flaw::InvalidScript));
 		//          }
 		if tokenizer.Err() != nil {
-			return &Payload{Invalid: InvalidScript}, InvalidScript.Error()
+			return &Payload{Invalid: InvalidScript, output: vout, offset: offset}, InvalidScript.Error()
 		}
 
-		return &Payload{Valid: payload}, nil
+		return &Payload{Valid: payload, output: vout}, nil
 	}
 
 	return nil, errors.New("no OP_RETURN output found")
@@ -406,35 +478,29 @@ func isPushBytes(opCode byte) bool {
 }
 
 func (r *Runestone) integers(payload []byte) ([]uint128.Uint128, error) {
-	integers := make([]uint128.Uint128, 0)
+	integers, _, err := decodeIntegers(payload)
+	if err != nil {
+		return nil, err
+	}
+	return integers, nil
+}
+
+// decodeIntegers decodes the varints of payload. offsets holds the byte
+// offset of every integer, followed by the offset where decoding stopped,
+// which is that of the invalid varint if err is not nil.
+func decodeIntegers(payload []byte) (integers []uint128.Uint128, offsets []int, err error) {
+	integers = make([]uint128.Uint128, 0)
 	i := 0
 
 	for i < len(payload) {
-		integer, length, err := uvarint128(payload[i:])
+		integer, length, err := Uvarint128(payload[i:])
 		if err != nil {
-			return nil, err
+			return integers, append(offsets, i), err
 		}
 		integers = append(integers, integer)
+		offsets = append(offsets, i)
 		i += length
 	}
 
-	return integers, nil
-}
-func uvarint128(buf []byte) (uint128.Uint128, int, error) {
-	n := big.NewInt(0)
-	for i, tick := range buf {
-		if i > 18 {
-			return uint128.Zero, 0, errors.New("varint too long")
-		}
-		value := uint64(tick) & 0b0111_1111
-		if i == 18 && value&0b0111_1100 != 0 {
-			return uint128.Zero, 0, errors.New("varint too large")
-		}
-		temp := new(big.Int).SetUint64(value)
-		n.Or(n, temp.Lsh(temp, uint(7*i)))
-		if tick&0b1000_0000 == 0 {
-			return uint128.FromBig(n), i + 1, nil
-		}
-	}
-	return uint128.Zero, 0, errors.New("varint too short")
+	return integers, append(offsets, i), nil
 }
@@ -189,7 +189,7 @@ func TestDecipheringValidRunestoneWithInvalidScriptPostfixReturnsInvalidPayload(
 		Value:    0,
 	})
 	runestone := &Runestone{}
-	payload, err := runestone.payload(tx)
+	payload, err := runestone.payload(tx, MAGIC_NUMBER)
 	assert.NotNil(t, payload)
 	assert.Equal(t, InvalidScript, payload.Invalid)
 }
@@ -1473,7 +1473,7 @@ func TestEncipher(t *testing.T) {
 			Version:  2,
 		}
 
-		payload, err := runestone.payload(transaction)
+		payload, err := runestone.payload(transaction, MAGIC_NUMBER)
 		if err != nil {
 			t.Fatalf("invalid payload: %v", err)
 		}
@@ -2045,3 +2045,72 @@ func TestAllNonPushdataOpcodesAreInvalid(t *testing.T) {
 		assertArtifactSame(t, expected, artifact)
 	}
 }
+
+func TestEncipherSortsEdictsByBlockAndTx(t *testing.T) {
+	runestone := &Runestone{Edicts: []Edict{
+		{ID: RuneId{Block: 2, Tx: 5}, Amount: uint128.From64(1), Output: 0},
+		{ID: RuneId{Block: 1, Tx: 9}, Amount: uint128.From64(2), Output: 0},
+		{ID: RuneId{Block: 2, Tx: 3}, Amount: uint128.From64(3), Output: 0},
+	}}
+	script, err := runestone.Encipher()
+	assert.NoError(t, err)
+	// 1:9, then 2:3 and 2:5 as deltas
+	assert.Equal(t, []byte{txscript.OP_RETURN, MAGIC_NUMBER, 13, 0, 1, 9, 2, 0, 1, 3, 3, 0, 0, 2, 1, 0}, script)
+	// the runestone itself is left as it was
+	assert.Equal(t, RuneId{Block: 2, Tx: 5}, runestone.Edicts[0].ID)
+}
+
+func TestEncipherOmitsUnsetTerms(t *testing.T) {
+	script, err := (&Runestone{Etching: &Etching{Terms: &Terms{Amount: Uint128PFrom64(5)}}}).Encipher()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{txscript.OP_RETURN, MAGIC_NUMBER, 4, byte(TagFlags), 3, byte(TagAmount), 5}, script)
+
+	script, err = (&Runestone{Etching: &Etching{Terms: &Terms{}}}).Encipher()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{txscript.OP_RETURN, MAGIC_NUMBER, 2, byte(TagFlags), 3}, script)
+}
+
+func TestEncipherPushesSingleByteChunksAsData(t *testing.T) {
+	// a body of 130 edicts is 521 bytes, leaving a last chunk of one byte
+	edicts := make([]Edict, 130)
+	edicts[129].Output = 1
+	runestone := &Runestone{Edicts: edicts}
+	script, err := runestone.Encipher()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{txscript.OP_DATA_1, 1}, script[len(script)-2:])
+
+	tx := &wire.MsgTx{TxOut: []*wire.TxOut{{PkScript: script}, {PkScript: []byte{txscript.OP_TRUE}}}}
+	artifact, err := runestone.Decipher(tx)
+	assert.NoError(t, err)
+	assertArtifactSame(t, &Artifact{Runestone: runestone}, artifact)
+}
+
+func TestCanonical(t *testing.T) {
+	caseFunc := func(script []byte, expected bool) {
+		tx := &wire.MsgTx{TxOut: []*wire.TxOut{{PkScript: script}, {PkScript: []byte{txscript.OP_TRUE}}}}
+		canonical, err := Canonical(tx)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, canonical, "%x", script)
+	}
+
+	r := Rune{Value: uint128.From64(1000)}
+	script, _ := (&Runestone{
+		Edicts:  []Edict{{ID: RuneId{Block: 1, Tx: 2}, Amount: uint128.From64(3), Output: 1}},
+		Etching: &Etching{Rune: &r, Premine: Uint128PFrom64(10)},
+		Pointer: Uint32P(1),
+	}).Encipher()
+	caseFunc(script, true)
+	caseFunc([]byte{txscript.OP_RETURN, MAGIC_NUMBER}, true)
+
+	// unknown odd tag
+	caseFunc([]byte{txscript.OP_RETURN, MAGIC_NUMBER, 2, byte(TagNop), 0}, false)
+	// overlong varint
+	caseFunc([]byte{txscript.OP_RETURN, MAGIC_NUMBER, 3, byte(TagPointer), 0x81, 0x00}, false)
+	// payload split over pushes
+	caseFunc([]byte{txscript.OP_RETURN, MAGIC_NUMBER, 1, byte(TagPointer), 1, 1}, false)
+	// cenotaph
+	caseFunc([]byte{txscript.OP_RETURN, MAGIC_NUMBER, 2, byte(TagCenotaph), 0}, false)
+
+	_, err := Canonical(&wire.MsgTx{TxOut: []*wire.TxOut{{PkScript: []byte{txscript.OP_RETURN}}}})
+	assert.ErrorIs(t, err, ErrNoRunestone)
+}
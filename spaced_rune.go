@@ -84,6 +84,21 @@ func SpacedRuneFromString(s string) (*SpacedRune, error) {
 	}, nil
 }
 
+// MarshalText encodes sr as its name with spacers, like "UNCOMMON•GOODS".
+func (sr SpacedRune) MarshalText() ([]byte, error) {
+	return []byte(sr.String()), nil
+}
+
+// UnmarshalText decodes a rune name with spacers.
+func (sr *SpacedRune) UnmarshalText(text []byte) error {
+	parsed, err := SpacedRuneFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*sr = *parsed
+	return nil
+}
+
 var (
 	ErrCharacter = func(c rune) error {
 		return fmt.Errorf("invalid character `%c`", c)
@@ -50,7 +50,31 @@ func NewTag(u uint128.Uint128) Tag {
 func (tag Tag) Byte() byte {
 	return byte(tag)
 }
+
+var tagToName = map[Tag]string{
+	TagBody:         "Body",
+	TagFlags:        "Flags",
+	TagRune:         "Rune",
+	TagPremine:      "Premine",
+	TagCap:          "Cap",
+	TagAmount:       "Amount",
+	TagHeightStart:  "HeightStart",
+	TagHeightEnd:    "HeightEnd",
+	TagOffsetStart:  "OffsetStart",
+	TagOffsetEnd:    "OffsetEnd",
+	TagMint:         "Mint",
+	TagPointer:      "Pointer",
+	TagCenotaph:     "Cenotaph",
+	TagDivisibility: "Divisibility",
+	TagSpacers:      "Spacers",
+	TagSymbol:       "Symbol",
+	TagNop:          "Nop",
+}
+
 func (tag Tag) String() string {
+	if name, ok := tagToName[tag]; ok {
+		return name
+	}
 	return fmt.Sprintf("Tag(%d)", tag)
 }
 
@@ -100,6 +124,6 @@ func TagTake[T any](t Tag, fields map[Tag][]uint128.Uint128, with func([]uint128
 func (t Tag) Encode(values []uint128.Uint128, payload *[]byte) {
 	for _, value := range values {
 		*payload = append(*payload, t.Byte())
-		*payload = append(*payload, EncodeUint128(value)...)
+		*payload = AppendUvarint128(*payload, value)
 	}
 }
@@ -0,0 +1,108 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient rune balance")
+	ErrTransferOutput      = errors.New("transfer to an output the transaction does not have")
+	ErrZeroTransfer        = errors.New("transfer of zero runes")
+)
+
+// Transfer sends Amount of the rune ID to the output at index Output.
+type Transfer struct {
+	ID     RuneId
+	Amount uint128.Uint128
+	Output uint32
+}
+
+// TransferPlan is the runestone carrying a set of transfers.
+type TransferPlan struct {
+	Runestone *Runestone
+	// Script is the enciphered Runestone, to be added as the last output of
+	// the transaction.
+	Script []byte
+	// Burned holds the runes the transaction would burn, by sending them to
+	// an OP_RETURN output. It is empty when nothing is burned.
+	Burned Balances
+}
+
+// PlanTransfer builds the runestone that makes tx send transfers out of the
+// runes held by its inputs, whose sum is inputs, and the rest to the output
+// at index change.
+//
+// tx holds every output but the runestone one, which is to be added last:
+// tx.AddTxOut(wire.NewTxOut(0, plan.Script)). Transfers of the same rune to
+// the same output are merged into a single edict, and the pointer is left out
+// when change is the output the remaining runes go to by default.
+func PlanTransfer(tx *wire.MsgTx, inputs Balances, transfers []Transfer, change uint32) (*TransferPlan, error) {
+	outputs := uint32(len(tx.TxOut))
+	if change >= outputs {
+		return nil, fmt.Errorf("%w: change output %d", ErrTransferOutput, change)
+	}
+
+	var edicts []Edict
+	index := make(map[Transfer]int)
+	spent := make(Balances)
+	for _, transfer := range transfers {
+		if transfer.Output >= outputs {
+			return nil, fmt.Errorf("%w: output %d", ErrTransferOutput, transfer.Output)
+		}
+		// an edict of zero allocates everything that is left
+		if transfer.Amount.IsZero() {
+			return nil, fmt.Errorf("%w: %s to output %d", ErrZeroTransfer, transfer.ID, transfer.Output)
+		}
+		total, err := checkedAdd(spent[transfer.ID], transfer.Amount)
+		if err != nil || total.Cmp(inputs[transfer.ID]) > 0 {
+			return nil, fmt.Errorf("%w: %s holds %s", ErrInsufficientBalance, transfer.ID, inputs[transfer.ID])
+		}
+		spent[transfer.ID] = total
+
+		key := Transfer{ID: transfer.ID, Output: transfer.Output}
+		if i, ok := index[key]; ok {
+			edicts[i].Amount = edicts[i].Amount.Add(transfer.Amount)
+			continue
+		}
+		index[key] = len(edicts)
+		edicts = append(edicts, Edict{ID: transfer.ID, Amount: transfer.Amount, Output: transfer.Output})
+	}
+
+	// in the order Encipher delta-encodes them, so that Runestone is what the
+	// script deciphers to
+	sort.SliceStable(edicts, func(i, j int) bool {
+		return edicts[i].ID.Cmp(edicts[j].ID) < 0
+	})
+	r := &Runestone{Edicts: edicts}
+	if int(change) != firstNonOpReturn(tx) {
+		r.Pointer = &change
+	}
+	script, err := r.Encipher()
+	if err != nil {
+		return nil, err
+	}
+
+	planned := tx.Copy()
+	planned.AddTxOut(wire.NewTxOut(0, script))
+	allocation := Allocate(planned, &Artifact{Runestone: r}, []Balances{inputs}, uint128.Zero, nil)
+	return &TransferPlan{Runestone: r, Script: script, Burned: allocation.Burned}, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+// transferTx returns a transaction with the given output scripts, an empty
+// one standing for a regular output.
+func transferTx(scripts ...[]byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	for _, script := range scripts {
+		if script == nil {
+			script = allocationPkScript
+		}
+		tx.AddTxOut(wire.NewTxOut(1000, script))
+	}
+	return tx
+}
+
+func TestPlanTransfer(t *testing.T) {
+	a := RuneId{Block: 840000, Tx: 7}
+	b := RuneId{Block: 840000, Tx: 3}
+	c := RuneId{Block: 840100, Tx: 1}
+	inputs := Balances{a: uint128.From64(100), b: uint128.From64(50), c: uint128.From64(5)}
+	tx := transferTx(nil, nil, nil)
+
+	plan, err := PlanTransfer(tx, inputs, []Transfer{
+		{ID: a, Amount: uint128.From64(30), Output: 1},
+		{ID: c, Amount: uint128.From64(5), Output: 2},
+		{ID: b, Amount: uint128.From64(20), Output: 1},
+		{ID: a, Amount: uint128.From64(10), Output: 1},
+	}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Burned)
+	// merged and sorted by rune id, with change going to output 0 by default
+	assert.Equal(t, &Runestone{Edicts: []Edict{
+		{ID: b, Amount: uint128.From64(20), Output: 1},
+		{ID: a, Amount: uint128.From64(40), Output: 1},
+		{ID: c, Amount: uint128.From64(5), Output: 2},
+	}}, plan.Runestone)
+
+	// the script is the enciphered runestone and moves runes as planned
+	tx.AddTxOut(wire.NewTxOut(0, plan.Script))
+	artifact, err := (&Runestone{}).Decipher(tx)
+	require.NoError(t, err)
+	require.NotNil(t, artifact.Runestone)
+	assert.Equal(t, plan.Runestone, artifact.Runestone)
+	allocation := Allocate(tx, artifact, []Balances{inputs}, uint128.Zero, nil)
+	assert.Equal(t, []Balances{
+		{a: uint128.From64(60), b: uint128.From64(30)},
+		{a: uint128.From64(40), b: uint128.From64(20)},
+		{c: uint128.From64(5)},
+		{},
+	}, allocation.Outputs)
+
+	plan, err = PlanTransfer(transferTx(nil, nil), inputs, []Transfer{{ID: a, Amount: uint128.From64(1), Output: 0}}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Uint32P(1), plan.Runestone.Pointer)
+}
+
+func TestPlanTransferBurns(t *testing.T) {
+	a := RuneId{Block: 840000, Tx: 7}
+	inputs := Balances{a: uint128.From64(100)}
+	opReturn := []byte{txscript.OP_RETURN}
+
+	plan, err := PlanTransfer(transferTx(nil, opReturn), inputs, []Transfer{{ID: a, Amount: uint128.From64(10), Output: 1}}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, Balances{a: uint128.From64(10)}, plan.Burned)
+
+	plan, err = PlanTransfer(transferTx(opReturn), inputs, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, Balances{a: uint128.From64(100)}, plan.Burned)
+}
+
+func TestPlanTransferErrors(t *testing.T) {
+	a := RuneId{Block: 840000, Tx: 7}
+	inputs := Balances{a: uint128.From64(100)}
+	tx := transferTx(nil, nil)
+
+	_, err := PlanTransfer(tx, inputs, []Transfer{
+		{ID: a, Amount: uint128.From64(60), Output: 0},
+		{ID: a, Amount: uint128.From64(41), Output: 1},
+	}, 0)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = PlanTransfer(tx, inputs, []Transfer{{ID: RuneId{Block: 1}, Amount: uint128.From64(1), Output: 0}}, 0)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = PlanTransfer(tx, Balances{a: uint128.Max}, []Transfer{
+		{ID: a, Amount: uint128.Max, Output: 0},
+		{ID: a, Amount: uint128.From64(1), Output: 1},
+	}, 0)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	_, err = PlanTransfer(tx, inputs, []Transfer{{ID: a, Amount: uint128.From64(1), Output: 2}}, 0)
+	assert.ErrorIs(t, err, ErrTransferOutput)
+	_, err = PlanTransfer(tx, inputs, nil, 2)
+	assert.ErrorIs(t, err, ErrTransferOutput)
+	_, err = PlanTransfer(tx, inputs, []Transfer{{ID: a, Output: 1}}, 0)
+	assert.ErrorIs(t, err, ErrZeroTransfer)
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"lukechampine.com/uint128"
+)
+
+// MaxNameLength is the length of the longest rune name, that of uint128.Max.
+const MaxNameLength = 28
+
+var (
+	ErrReservedRune   = errors.New("reserved runes cannot be etched by name")
+	ErrInvalidLength  = fmt.Errorf("rune name length must be between 1 and %d", MaxNameLength)
+	ErrNoUnlockLength = errors.New("names of this length are all reserved")
+	ErrRuneLocked     = errors.New("rune name is not unlocked yet")
+	ErrRuneEtched     = errors.New("rune has already been etched")
+	ErrRunesInactive  = errors.New("runes are not active at this height")
+)
+
+// UnlockHeight returns the first block height at which r can be etched on c:
+// the lowest height from FirstRuneHeight on whose MinimumAtHeight is not
+// above r.
+func (c *Chain) UnlockHeight(r Rune) (uint64, error) {
+	if r.IsReserved() {
+		return 0, ErrReservedRune
+	}
+	// MinimumAtHeight only decreases with height and reaches zero in the
+	// block before the end of the first halving after FirstRuneHeight.
+	start := c.FirstRuneHeight
+	return start + uint64(sort.Search(int(c.SubsidyHalvingInterval), func(i int) bool {
+		return c.MinimumAtHeight(start+uint64(i)).Value.Cmp(r.Value) <= 0
+	})), nil
+}
+
+// NameLengthUnlockHeight returns the first block height at which any name of
+// length can be etched on c. The longest names of a length, those closest to
+// the next length, unlock first.
+func (c *Chain) NameLengthUnlockHeight(length int) (uint64, error) {
+	first, _, err := nameLengthBounds(length)
+	if err != nil {
+		return 0, err
+	}
+	return c.UnlockHeight(first)
+}
+
+// UnlockStep is the range of heights over which the names of one length
+// unlock: the first of them at Start and all of them at End.
+type UnlockStep struct {
+	Length int
+	Start  uint64
+	End    uint64
+}
+
+// UnlockSchedule returns when the names of every length that is not entirely
+// reserved unlock on c, from the shortest to the longest.
+func (c *Chain) UnlockSchedule() []UnlockStep {
+	var schedule []UnlockStep
+	for length := 1; length <= MaxNameLength; length++ {
+		first, last, err := nameLengthBounds(length)
+		if err != nil {
+			break
+		}
+		start, _ := c.UnlockHeight(first)
+		end, _ := c.UnlockHeight(last)
+		schedule = append(schedule, UnlockStep{Length: length, Start: start, End: end})
+	}
+	return schedule
+}
+
+// nameLengthBounds returns the first and the last rune to unlock among the
+// names of length, leaving out reserved runes.
+func nameLengthBounds(length int) (first, last Rune, err error) {
+	if length < 1 || length > MaxNameLength {
+		return Rune{}, Rune{}, ErrInvalidLength
+	}
+	last = Rune{Value: STEPS[length-1]}
+	if last.IsReserved() {
+		return Rune{}, Rune{}, ErrNoUnlockLength
+	}
+	if length == MaxNameLength {
+		first = Rune{Value: uint128.Max}
+	} else {
+		first = Rune{Value: STEPS[length].Sub64(1)}
+	}
+	if first.IsReserved() {
+		first = Rune{Value: RESERVED.Sub64(1)}
+	}
+	return first, last, nil
+}
+
+// RuneLookup finds runes that have already been etched. *index.Index
+// implements it.
+type RuneLookup interface {
+	// RuneId returns the id of the rune named r, or nil if it was never
+	// etched.
+	RuneId(r Rune) (*RuneId, error)
+}
+
+// ValidateEtchingName checks that an etching of r in the block at height on
+// chain will create it, and reports whether its transaction has to reveal a
+// commitment to r. It fails with ErrRunesInactive below the first rune height
+// of chain, where nothing can be etched, with ErrReservedRune for reserved
+// names, with ErrRuneLocked for names below MinimumAtHeight and with
+// ErrRuneEtched for names that lookup already knows; lookup is optional.
+//
+// A nil r is an unnamed etching, which gets a reserved rune and needs no
+// commitment.
+func ValidateEtchingName(r *Rune, height uint64, chain *Chain, lookup RuneLookup) (commitment bool, err error) {
+	if height < chain.FirstRuneHeight {
+		return r != nil, fmt.Errorf("%w: block %d is below the first rune height %d", ErrRunesInactive, height, chain.FirstRuneHeight)
+	}
+	if r == nil {
+		return false, nil
+	}
+	if r.IsReserved() {
+		return true, fmt.Errorf("%w: %s", ErrReservedRune, r)
+	}
+	if r.Value.Cmp(chain.MinimumAtHeight(height).Value) < 0 {
+		unlock, _ := chain.UnlockHeight(*r)
+		return true, fmt.Errorf("%w: %s unlocks at block %d", ErrRuneLocked, r, unlock)
+	}
+	if lookup != nil {
+		id, err := lookup.RuneId(*r)
+		if err != nil {
+			return true, err
+		}
+		if id != nil {
+			return true, fmt.Errorf("%w: %s is %s", ErrRuneEtched, r, id)
+		}
+	}
+	return true, nil
+}
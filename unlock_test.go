@@ -0,0 +1,130 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnlockHeight(t *testing.T) {
+	caseFunc := func(chain *Chain, s string, expected uint64) {
+		r, err := RuneFromString(s)
+		assert.NoError(t, err)
+		height, err := chain.UnlockHeight(*r)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, height, s)
+		assert.True(t, chain.MinimumAtHeight(height).Value.Cmp(r.Value) <= 0, s)
+		if height > chain.FirstRuneHeight {
+			assert.True(t, chain.MinimumAtHeight(height-1).Value.Cmp(r.Value) > 0, s)
+		}
+	}
+
+	caseFunc(Mainnet, "AAAAAAAAAAAAAA", 840000)
+	caseFunc(Mainnet, "ZZZYZBRRWXXH", 840000)
+	caseFunc(Mainnet, "AAAAAAAAAAAA", 857499)
+	caseFunc(Mainnet, "ZZZZZZZZZZZ", 857500)
+	caseFunc(Mainnet, "Z", 1033173)
+	caseFunc(Mainnet, "A", 1049999)
+	caseFunc(Testnet3, "AAAAAAAAAAAA", uint64(SUBSIDY_HALVING_INTERVAL)*12+17499)
+	caseFunc(Signet, "AAAAAAAAAAAA", 17499)
+
+	_, err := Mainnet.UnlockHeight(Reserved(0, 0))
+	assert.ErrorIs(t, err, ErrReservedRune)
+}
+
+func TestNameLengthUnlockHeight(t *testing.T) {
+	height, err := Mainnet.NameLengthUnlockHeight(12)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(840000), height)
+
+	height, err = Mainnet.NameLengthUnlockHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1033173), height)
+
+	height, err = Mainnet.NameLengthUnlockHeight(26)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(840000), height)
+
+	_, err = Mainnet.NameLengthUnlockHeight(27)
+	assert.ErrorIs(t, err, ErrNoUnlockLength)
+	_, err = Mainnet.NameLengthUnlockHeight(0)
+	assert.ErrorIs(t, err, ErrInvalidLength)
+	_, err = Mainnet.NameLengthUnlockHeight(MaxNameLength + 1)
+	assert.ErrorIs(t, err, ErrInvalidLength)
+}
+
+func TestUnlockSchedule(t *testing.T) {
+	schedule := Mainnet.UnlockSchedule()
+	assert.Len(t, schedule, 26)
+	assert.Equal(t, UnlockStep{Length: 1, Start: 1033173, End: 1049999}, schedule[0])
+	assert.Equal(t, UnlockStep{Length: 12, Start: 840000, End: 857499}, schedule[11])
+	assert.Equal(t, UnlockStep{Length: 13, Start: 840000, End: 840000}, schedule[12])
+	for i := 1; i < 12; i++ {
+		// a length only starts unlocking once every longer name has
+		assert.Greater(t, schedule[i-1].Start, schedule[i].End, schedule[i-1])
+		assert.LessOrEqual(t, schedule[i].Start, schedule[i].End, schedule[i])
+	}
+}
+
+type runeLookup map[Rune]RuneId
+
+func (l runeLookup) RuneId(r Rune) (*RuneId, error) {
+	id, ok := l[r]
+	if !ok {
+		return nil, nil
+	}
+	return &id, nil
+}
+
+func TestValidateEtchingName(t *testing.T) {
+	name := func(s string) *Rune {
+		r, err := RuneFromString(s)
+		require.NoError(t, err)
+		return r
+	}
+
+	commitment, err := ValidateEtchingName(name("AAAAAAAAAAAAA"), 840000, Mainnet, nil)
+	assert.NoError(t, err)
+	assert.True(t, commitment)
+
+	commitment, err = ValidateEtchingName(nil, 840000, Mainnet, nil)
+	assert.NoError(t, err)
+	assert.False(t, commitment)
+
+	_, err = ValidateEtchingName(name("AAAAAAAAAAAA"), 857498, Mainnet, nil)
+	assert.ErrorIs(t, err, ErrRuneLocked)
+	assert.ErrorContains(t, err, "unlocks at block 857499")
+	_, err = ValidateEtchingName(name("AAAAAAAAAAAA"), 857499, Mainnet, nil)
+	assert.NoError(t, err)
+	_, err = ValidateEtchingName(name("A"), 1000, Signet, nil)
+	assert.ErrorIs(t, err, ErrRuneLocked)
+	_, err = ValidateEtchingName(name("AAAAAAAAAAAAAAAAAA"), 839999, Mainnet, nil)
+	assert.ErrorIs(t, err, ErrRunesInactive)
+	_, err = ValidateEtchingName(nil, 839999, Mainnet, nil)
+	assert.ErrorIs(t, err, ErrRunesInactive)
+
+	reserved := Reserved(840000, 1)
+	_, err = ValidateEtchingName(&reserved, 840000, Mainnet, nil)
+	assert.ErrorIs(t, err, ErrReservedRune)
+
+	lookup := runeLookup{*name("UNCOMMONGOODS"): {Block: 1, Tx: 0}}
+	_, err = ValidateEtchingName(name("UNCOMMONGOODS"), 840000, Mainnet, lookup)
+	assert.ErrorIs(t, err, ErrRuneEtched)
+	_, err = ValidateEtchingName(name("UNCOMMONGOODT"), 840000, Mainnet, lookup)
+	assert.NoError(t, err)
+}
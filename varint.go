@@ -0,0 +1,96 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"errors"
+	"io"
+
+	"lukechampine.com/uint128"
+)
+
+var (
+	// ErrVarintOverlong is returned for a varint longer than the 19 bytes
+	// needed to encode any uint128.
+	ErrVarintOverlong = errors.New("varint too long")
+	// ErrVarintOverflow is returned for a 19 byte varint whose value does not
+	// fit in 128 bits.
+	ErrVarintOverflow = errors.New("varint too large")
+	// ErrVarintUnterminated is returned when the buffer ends in the middle of
+	// a varint.
+	ErrVarintUnterminated = errors.New("varint too short")
+)
+
+// MaxVarintLen128 is the maximum length of a LEB128 encoded uint128.
+const MaxVarintLen128 = 19
+
+// AppendUvarint128 appends the LEB128 encoding of v to dst and returns the
+// extended buffer.
+func AppendUvarint128(dst []byte, v uint128.Uint128) []byte {
+	for v.Hi != 0 || v.Lo >= 0x80 {
+		dst = append(dst, byte(v.Lo)|0x80)
+		v = v.Rsh(7)
+	}
+	return append(dst, byte(v.Lo))
+}
+
+// Uvarint128 decodes a LEB128 encoded uint128 from the start of buf and
+// returns it with the number of bytes read. Like ord, it rejects varints
+// longer than MaxVarintLen128 bytes and values overflowing 128 bits, but
+// accepts non-minimal encodings.
+func Uvarint128(buf []byte) (uint128.Uint128, int, error) {
+	var n uint128.Uint128
+	for i, b := range buf {
+		if i == MaxVarintLen128 {
+			return uint128.Zero, 0, ErrVarintOverlong
+		}
+		value := uint64(b & 0b0111_1111)
+		if i == MaxVarintLen128-1 && value&0b0111_1100 != 0 {
+			return uint128.Zero, 0, ErrVarintOverflow
+		}
+		n = n.Or(uint128.From64(value).Lsh(uint(7 * i)))
+		if b&0b1000_0000 == 0 {
+			return n, i + 1, nil
+		}
+	}
+	return uint128.Zero, 0, ErrVarintUnterminated
+}
+
+// ReadUvarint128 reads a LEB128 encoded uint128 from r, with the limits of
+// Uvarint128. Like binary.ReadUvarint, it returns io.EOF only if no byte was
+// read and io.ErrUnexpectedEOF if r ends in the middle of the varint.
+func ReadUvarint128(r io.ByteReader) (uint128.Uint128, error) {
+	var n uint128.Uint128
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			if i > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return uint128.Zero, err
+		}
+		if i == MaxVarintLen128 {
+			return uint128.Zero, ErrVarintOverlong
+		}
+		value := uint64(b & 0b0111_1111)
+		if i == MaxVarintLen128-1 && value&0b0111_1100 != 0 {
+			return uint128.Zero, ErrVarintOverflow
+		}
+		n = n.Or(uint128.From64(value).Lsh(uint(7 * i)))
+		if b&0b1000_0000 == 0 {
+			return n, nil
+		}
+	}
+}
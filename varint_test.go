@@ -0,0 +1,153 @@
+// Copyright 2024 The BxELab studyzy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runestone
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+func TestUvarint128RoundTrip(t *testing.T) {
+	values := []uint128.Uint128{
+		uint128.Zero,
+		uint128.From64(1),
+		uint128.From64(127),
+		uint128.From64(128),
+		uint128.From64(^uint64(0)),
+		uint128.New(0, 1),
+		uint128.Max,
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		values = append(values, uint128.New(rng.Uint64(), rng.Uint64()).Rsh(uint(rng.Intn(128))))
+	}
+	for _, v := range values {
+		encoded := AppendUvarint128(nil, v)
+		assert.Equal(t, Encode(v.Big()), encoded, v)
+
+		decoded, length, err := Uvarint128(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, v, decoded)
+		assert.Equal(t, len(encoded), length)
+
+		read, err := ReadUvarint128(bytes.NewReader(encoded))
+		require.NoError(t, err)
+		assert.Equal(t, v, read)
+	}
+
+	assert.Equal(t, []byte{0xaa, 0x80, 0x01}, AppendUvarint128([]byte{0xaa}, uint128.From64(128)))
+	assert.Len(t, AppendUvarint128(nil, uint128.Max), MaxVarintLen128)
+}
+
+func TestUvarint128Errors(t *testing.T) {
+	max := bytes.Repeat([]byte{0xff}, MaxVarintLen128)
+	max[MaxVarintLen128-1] = 0x03
+	v, length, err := Uvarint128(max)
+	require.NoError(t, err)
+	assert.Equal(t, uint128.Max, v)
+	assert.Equal(t, MaxVarintLen128, length)
+
+	overflow := bytes.Clone(max)
+	overflow[MaxVarintLen128-1] = 0x04
+	_, _, err = Uvarint128(overflow)
+	assert.ErrorIs(t, err, ErrVarintOverflow)
+	_, err = ReadUvarint128(bytes.NewReader(overflow))
+	assert.ErrorIs(t, err, ErrVarintOverflow)
+
+	overlong := append(bytes.Repeat([]byte{0x80}, MaxVarintLen128), 0x00)
+	_, _, err = Uvarint128(overlong)
+	assert.ErrorIs(t, err, ErrVarintOverlong)
+	_, err = ReadUvarint128(bytes.NewReader(overlong))
+	assert.ErrorIs(t, err, ErrVarintOverlong)
+
+	_, _, err = Uvarint128([]byte{0x80})
+	assert.ErrorIs(t, err, ErrVarintUnterminated)
+	_, _, err = Uvarint128(nil)
+	assert.ErrorIs(t, err, ErrVarintUnterminated)
+	_, err = ReadUvarint128(bytes.NewReader([]byte{0x80}))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = ReadUvarint128(bytes.NewReader(nil))
+	assert.ErrorIs(t, err, io.EOF)
+
+	// non-minimal encodings are accepted, as in ord
+	v, length, err = Uvarint128([]byte{0x80, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, uint128.Zero, v)
+	assert.Equal(t, 2, length)
+}
+
+func TestReadUvarint128Stream(t *testing.T) {
+	var buf []byte
+	for i := uint64(0); i < 300; i += 50 {
+		buf = AppendUvarint128(buf, uint128.From64(i))
+	}
+	r := bytes.NewReader(buf)
+	for i := uint64(0); i < 300; i += 50 {
+		v, err := ReadUvarint128(r)
+		require.NoError(t, err)
+		assert.Equal(t, uint128.From64(i), v)
+	}
+	_, err := ReadUvarint128(r)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func BenchmarkAppendUvarint128(b *testing.B) {
+	buf := make([]byte, 0, MaxVarintLen128)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendUvarint128(buf[:0], uint128.Max)
+	}
+}
+
+func BenchmarkUvarint128(b *testing.B) {
+	buf := AppendUvarint128(nil, uint128.Max)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Uvarint128(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecipher(b *testing.B) {
+	r := Rune{Value: uint128.New(0xfedcba9876543210, 0x0123456789abcdef)}
+	edicts := make([]Edict, 16)
+	for i := range edicts {
+		edicts[i] = Edict{ID: RuneId{Block: 840000 + uint64(i), Tx: uint32(i)}, Amount: uint128.New(^uint64(0), uint64(i)), Output: 1}
+	}
+	script, err := (&Runestone{
+		Etching: &Etching{Rune: &r, Premine: Uint128PFrom64(21_000_000_00000000)},
+		Edicts:  edicts,
+	}).Encipher()
+	if err != nil {
+		b.Fatal(err)
+	}
+	tx := &wire.MsgTx{TxOut: []*wire.TxOut{{PkScript: script}, {PkScript: []byte{txscript.OP_TRUE}}}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := (&Runestone{}).Decipher(tx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}